@@ -0,0 +1,41 @@
+package pkg
+
+// AnyPromise is implemented by every Promise[T], whatever T is, so promises of
+// different types can be passed to one heterogeneous combinator such as AllSettledAny.
+type AnyPromise interface {
+	// Settle blocks until the promise settles and returns its outcome with the
+	// value boxed into any.
+	Settle() PromiseResult[any]
+}
+
+// Settle blocks until the promise settles and returns its outcome with the value
+// boxed into any, which lets Promise[T] satisfy AnyPromise without reflection.
+// Like Await, it reports ErrAwaitDeadlock when called from the promise's own executor
+// if deadlock detection is enabled.
+func (p *Promise[T]) Settle() PromiseResult[any] {
+	value, err := p.Await()
+	if err != nil {
+		return PromiseResult[any]{Error: err}
+	}
+	return PromiseResult[any]{Value: value, Fulfilled: true}
+}
+
+// AllSettledAny is AllSettled for promises of different types. It waits until all
+// promises have settled and resolves with their outcomes in order, with each value
+// boxed into any. It never rejects.
+func AllSettledAny(promises ...AnyPromise) *Promise[[]PromiseResult[any]] {
+	return NewPromise[[]PromiseResult[any]](func(resolve func([]PromiseResult[any]), reject func(error), finally func()) {
+		results := make([]PromiseResult[any], len(promises))
+		settled := make(chan struct{}, len(promises))
+		for i, p := range promises {
+			goTracked(func() {
+				results[i] = p.Settle()
+				settled <- struct{}{}
+			})
+		}
+		for range promises {
+			<-settled
+		}
+		resolve(results)
+	})
+}
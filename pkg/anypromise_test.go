@@ -0,0 +1,30 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAllSettledAnyMixedTypes(t *testing.T) {
+	boom := errors.New("boom")
+	results, err := AllSettledAny(Resolve(1), Resolve("two"), Resolve(true), Reject[bool](boom)).Await()
+	if err != nil {
+		t.Fatalf("AllSettledAny rejected with %v, want it never to reject", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+
+	if r := results[0]; !r.Fulfilled || r.Value.(int) != 1 {
+		t.Errorf("results[0] = %+v, want fulfilled with int 1", r)
+	}
+	if r := results[1]; !r.Fulfilled || r.Value.(string) != "two" {
+		t.Errorf("results[1] = %+v, want fulfilled with string two", r)
+	}
+	if r := results[2]; !r.Fulfilled || !r.Value.(bool) {
+		t.Errorf("results[2] = %+v, want fulfilled with bool true", r)
+	}
+	if r := results[3]; r.Fulfilled || !errors.Is(r.Error, boom) {
+		t.Errorf("results[3] = %+v, want rejected with boom", r)
+	}
+}
@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Await blocks until the promise settles and returns its value and error.
+// Every call observes the same cached outcome, which is written exactly once
+// when the promise first settles.
+// Calling Await on a pending promise from its own executor would never return;
+// with SetDeadlockDetection enabled, Await returns ErrAwaitDeadlock instead.
+func (p *Promise[T]) Await() (T, error) {
+	select {
+	case <-p.done:
+		return p.view(p.value), p.err
+	default:
+	}
+
+	if id := p.executorGoroutine.Load(); id != 0 && id == goroutineID() {
+		var zero T
+		return zero, ErrAwaitDeadlock
+	}
+	return p.await()
+}
+
+// AwaitContext is Await with a way to give up: it returns the promise's outcome if
+// the promise settles first, or an error matching ErrAwaitAbandoned if ctx ends first.
+// The abandoned error also wraps ctx.Err(), so errors.Is can tell a deadline from a
+// cancellation, while an error that does not match ErrAwaitAbandoned is always the
+// promise's own rejection reason. Abandoning the wait does not cancel the promise.
+// Like Await, it reports ErrAwaitDeadlock when called from the promise's own executor
+// if deadlock detection is enabled.
+func (p *Promise[T]) AwaitContext(ctx context.Context) (T, error) {
+	var zero T
+	select {
+	case <-p.done:
+		return p.view(p.value), p.err
+	default:
+	}
+
+	if id := p.executorGoroutine.Load(); id != 0 && id == goroutineID() {
+		return zero, ErrAwaitDeadlock
+	}
+	select {
+	case <-p.Done():
+		return p.view(p.value), p.err
+	case <-ctx.Done():
+		return zero, fmt.Errorf("%w: %w", ErrAwaitAbandoned, ctx.Err())
+	}
+}
+
+// Result blocks until the promise settles and returns its outcome as a PromiseResult.
+// Like Await, it reports ErrAwaitDeadlock when called from the promise's own executor
+// if deadlock detection is enabled.
+func (p *Promise[T]) Result() PromiseResult[T] {
+	value, err := p.Await()
+	return PromiseResult[T]{Value: value, Error: err, Fulfilled: err == nil}
+}
+
+// Done returns a channel that is closed once the promise settles.
+// Every caller receives the same channel and settlement closes it once, which
+// wakes all waiters together, so settling costs the same for one waiter or for
+// thousands.
+func (p *Promise[T]) Done() <-chan struct{} {
+	p.start()
+	return p.done
+}
+
+// ToChannel returns a value channel and an error channel for the promise.
+// When the promise settles, its value or its error is sent on the matching channel
+// and then both channels are closed. The channels are buffered with size 1, so the
+// outcome is held until it is read and delivering it never blocks, whether the
+// reader arrives before or after settlement.
+func (p *Promise[T]) ToChannel() (<-chan T, <-chan error) {
+	values := make(chan T, 1)
+	errs := make(chan error, 1)
+	p.whenSettled(func(value T, err error) {
+		defer close(values)
+		defer close(errs)
+		if err != nil {
+			errs <- err
+			return
+		}
+		values <- value
+	})
+	return values, errs
+}
+
+// AddTo adds the promise to wg: it calls wg.Add(1) straight away and wg.Done once
+// the promise settles, so promises can join existing WaitGroup-based wait points.
+func (p *Promise[T]) AddTo(wg *sync.WaitGroup) {
+	wg.Add(1)
+	p.whenSettled(func(T, error) {
+		wg.Done()
+	})
+}
+
+// Peek returns the outcome of the promise without blocking.
+// The boolean is false if the promise is still pending.
+func (p *Promise[T]) Peek() (PromiseResult[T], bool) {
+	select {
+	case <-p.done:
+		return p.Result(), true
+	default:
+		return PromiseResult[T]{}, false
+	}
+}
+
+// AwaitAll blocks until every promise has settled and returns the fulfilled values
+// and the rejection errors separately, each in the order of the input promises.
+// It is a synchronous convenience for scripts and tests.
+func AwaitAll[T any](promises ...*Promise[T]) ([]T, []error) {
+	var values []T
+	var errs []error
+	for _, p := range promises {
+		value, err := p.await()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values = append(values, value)
+	}
+	return values, errs
+}
+
+// AwaitOrElse blocks until p settles and returns its value, or the result of
+// fallback applied to the rejection error if p rejects. It guarantees a value
+// in synchronous code, such as a default when loading configuration fails.
+func AwaitOrElse[T any](p *Promise[T], fallback func(error) T) T {
+	value, err := p.Await()
+	if err != nil {
+		return fallback(err)
+	}
+	return value
+}
+
+// AwaitBounded acquires a slot in sem before blocking on p and releases it once p
+// has settled, limiting how many goroutines can be blocked awaiting at the same time.
+// sem is a counting semaphore: its capacity is the number of slots, and a slot is
+// held by sending a value on it.
+func AwaitBounded[T any](sem chan struct{}, p *Promise[T]) (T, error) {
+	sem <- struct{}{}
+	defer func() { <-sem }()
+	return p.Await()
+}
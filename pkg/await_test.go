@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAwaitDeadlockDetection(t *testing.T) {
+	SetDeadlockDetection(true)
+	defer SetDeadlockDetection(false)
+
+	var p *Promise[int]
+	ready := make(chan struct{})
+	var got error
+	p = NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+		<-ready
+		_, got = p.Await()
+		resolve(1)
+	})
+	close(ready)
+	p.Await()
+	if !errors.Is(got, ErrAwaitDeadlock) {
+		t.Fatalf("Await from own executor = %v, want ErrAwaitDeadlock", got)
+	}
+}
+
+// BenchmarkDoneManyWaiters measures settling a promise that 10,000 goroutines are
+// blocked on through Done, from resolve until the last waiter has woken.
+func BenchmarkDoneManyWaiters(b *testing.B) {
+	const waiters = 10000
+	for range b.N {
+		b.StopTimer()
+		p, resolve := delayed[int]()
+		var ready, woken sync.WaitGroup
+		ready.Add(waiters)
+		woken.Add(waiters)
+		for range waiters {
+			go func() {
+				done := p.Done()
+				ready.Done()
+				<-done
+				woken.Done()
+			}()
+		}
+		ready.Wait()
+		b.StartTimer()
+
+		resolve(1)
+		woken.Wait()
+	}
+}
+
+func TestConcurrentAwaitersSeeSameOutcome(t *testing.T) {
+	const awaiters = 100
+	p, resolve := delayed[int]()
+
+	var wg sync.WaitGroup
+	errs := make(chan string, awaiters)
+	for i := range awaiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Mix the accessors so they all read the cached outcome concurrently.
+			switch i % 3 {
+			case 0:
+				if value, err := p.Await(); err != nil || value != 42 {
+					errs <- fmt.Sprintf("Await = %d, %v", value, err)
+				}
+			case 1:
+				if r := p.Result(); !r.Fulfilled || r.Value != 42 {
+					errs <- fmt.Sprintf("Result = %+v", r)
+				}
+			default:
+				<-p.Done()
+				if r, ok := p.Peek(); !ok || r.Value != 42 {
+					errs <- fmt.Sprintf("Peek = %+v, %v", r, ok)
+				}
+			}
+		}()
+	}
+
+	resolve(42)
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+}
+
+func TestToChannelReadAfterSettle(t *testing.T) {
+	p := Resolve(7)
+	values, errs := p.ToChannel()
+	// The buffered channels hold the outcome until it is read, so reading late
+	// must still deliver it.
+	<-p.Done()
+	time.Sleep(10 * time.Millisecond)
+
+	if value, ok := <-values; !ok || value != 7 {
+		t.Fatalf("value = %d, %v; want 7, true", value, ok)
+	}
+	if err, ok := <-errs; ok {
+		t.Fatalf("error channel delivered %v, want it closed", err)
+	}
+}
+
+func TestToChannelReadBeforeSettle(t *testing.T) {
+	boom := errors.New("boom")
+	gate := make(chan struct{})
+	p := NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+		<-gate
+		reject(boom)
+	})
+	values, errs := p.ToChannel()
+
+	// The reader is already waiting on the channel when the promise rejects.
+	received := make(chan error)
+	go func() { received <- <-errs }()
+	close(gate)
+
+	if err := <-received; !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want boom", err)
+	}
+	if value, ok := <-values; ok {
+		t.Fatalf("value channel delivered %d, want it closed", value)
+	}
+}
+
+func TestAwaitContextAbandoned(t *testing.T) {
+	p, resolve := delayed[int]()
+	defer resolve(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := p.AwaitContext(ctx)
+	if !errors.Is(err, ErrAwaitAbandoned) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AwaitContext error = %v, want ErrAwaitAbandoned wrapping DeadlineExceeded", err)
+	}
+	if _, settled := p.Peek(); settled {
+		t.Fatal("abandoning the wait settled the promise")
+	}
+}
+
+func TestAwaitContextSettles(t *testing.T) {
+	boom := errors.New("boom")
+	ctx := context.Background()
+
+	if value, err := Resolve(5).AwaitContext(ctx); err != nil || value != 5 {
+		t.Fatalf("fulfilled: AwaitContext = %d, %v; want 5, nil", value, err)
+	}
+
+	p, resolve := delayed[int]()
+	go resolve(6)
+	if value, err := p.AwaitContext(ctx); err != nil || value != 6 {
+		t.Fatalf("pending: AwaitContext = %d, %v; want 6, nil", value, err)
+	}
+
+	_, err := Reject[int](boom).AwaitContext(ctx)
+	if !errors.Is(err, boom) || errors.Is(err, ErrAwaitAbandoned) {
+		t.Fatalf("rejected: AwaitContext error = %v, want boom and not ErrAwaitAbandoned", err)
+	}
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyedBatcher groups submitted items by key and flushes each key's batch once
+// its window has elapsed. Every submitter of a batch receives the same promise,
+// which settles with the outcome of flushing that batch.
+type KeyedBatcher[K comparable, I, O any] struct {
+	window time.Duration
+	flush  func(K, []I) (O, error)
+
+	mutex   sync.Mutex
+	batches map[K]*keyedBatch[I, O]
+}
+
+// keyedBatch holds the items collected for one key during a window.
+type keyedBatch[I, O any] struct {
+	items   []I
+	promise *Promise[O]
+}
+
+// NewKeyedBatcher creates a KeyedBatcher that calls flush with the items collected
+// for a key, window after the first item for that key was added.
+func NewKeyedBatcher[K comparable, I, O any](window time.Duration, flush func(K, []I) (O, error)) *KeyedBatcher[K, I, O] {
+	return &KeyedBatcher[K, I, O]{
+		window:  window,
+		flush:   flush,
+		batches: make(map[K]*keyedBatch[I, O]),
+	}
+}
+
+// Add submits item to the current batch for key, starting a new batch and its
+// window if there is none. It returns the promise shared by everyone in the batch.
+func (b *KeyedBatcher[K, I, O]) Add(key K, item I) *Promise[O] {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	batch, ok := b.batches[key]
+	if !ok {
+		batch = &keyedBatch[I, O]{promise: newPromise[O](defaultGroup, context.Background())}
+		b.batches[key] = batch
+		time.AfterFunc(b.window, func() {
+			b.flushBatch(key, batch)
+		})
+	}
+	batch.items = append(batch.items, item)
+	return batch.promise
+}
+
+// flushBatch closes the batch for key and settles its promise with the flush result.
+// flush runs as the executor of the batch's promise, so a panicking flush rejects
+// the batch with a *PanicError under the panic policy, like any other executor.
+func (b *KeyedBatcher[K, I, O]) flushBatch(key K, batch *keyedBatch[I, O]) {
+	b.mutex.Lock()
+	delete(b.batches, key)
+	b.mutex.Unlock()
+
+	batch.promise.execute(func(resolve func(O), reject func(error), finally func()) {
+		out, err := b.flush(key, batch.items)
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(out)
+	})
+}
@@ -0,0 +1,38 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyedBatcherFlushesBatch(t *testing.T) {
+	batcher := NewKeyedBatcher(10*time.Millisecond, func(key string, items []int) (int, error) {
+		sum := 0
+		for _, item := range items {
+			sum += item
+		}
+		return sum, nil
+	})
+
+	first := batcher.Add("a", 1)
+	second := batcher.Add("a", 2)
+	if first != second {
+		t.Fatal("items added within one window received different promises")
+	}
+	if sum, err := first.Await(); err != nil || sum != 3 {
+		t.Fatalf("Await = %d, %v; want 3, nil", sum, err)
+	}
+}
+
+func TestKeyedBatcherFlushPanicRejects(t *testing.T) {
+	batcher := NewKeyedBatcher(time.Millisecond, func(key string, items []int) (int, error) {
+		panic("boom")
+	})
+
+	_, err := batcher.Add("a", 1).Await()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("Await error = %v, want a *PanicError for boom", err)
+	}
+}
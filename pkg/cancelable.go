@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"context"
+
+	"promise/pkg/contract"
+)
+
+// CancelablePromise is a Promise that its consumer can cancel with a reason.
+type CancelablePromise[T any] struct {
+	*Promise[T]
+}
+
+// NewCancelablePromise creates a promise bound to ctx, like NewPromiseWithContext,
+// that can also be canceled through Cancel.
+func NewCancelablePromise[T any](ctx context.Context, executor contract.ContextExecutorFunc[T]) *CancelablePromise[T] {
+	return &CancelablePromise[T]{Promise: NewPromiseWithContext(ctx, executor)}
+}
+
+// Cancel cancels the promise's context and rejects the promise with reason,
+// or with ErrCanceled if reason is nil. The executor can read the reason with
+// context.Cause on its context. Cancel has no effect once the promise has settled.
+func (p *CancelablePromise[T]) Cancel(reason error) {
+	if reason == nil {
+		reason = ErrCanceled
+	}
+	p.cancelWith(reason)
+	p.reject(reason)
+}
+
+// AllCancelable waits for all promises like All, and also returns a function that
+// aborts the whole batch when its results are no longer needed. Calling it cancels
+// every input promise that is still pending with ErrCanceled, so the aggregate
+// rejects with an error matching context.Canceled unless it has already settled.
+func AllCancelable[T any](promises ...*CancelablePromise[T]) (*Promise[[]T], context.CancelFunc) {
+	inputs := make([]*Promise[T], len(promises))
+	for i, p := range promises {
+		inputs[i] = p.Promise
+	}
+	cancel := func() {
+		for _, p := range promises {
+			p.Cancel(nil)
+		}
+	}
+	return All(inputs...), cancel
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCancelSettledPromiseLeavesContext(t *testing.T) {
+	release := make(chan struct{})
+	p := NewCancelablePromise[int](context.Background(), func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+	})
+	// Hold the context so that it is not released after settlement.
+	step := ChainCtx(p.Promise, func(ctx context.Context, value int) *Promise[int] {
+		return NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+			<-release
+			resolve(value)
+		})
+	})
+	p.Await()
+
+	p.Cancel(errors.New("too late"))
+	if err := p.Context().Err(); err != nil {
+		t.Fatalf("Cancel after settlement canceled the context: %v", err)
+	}
+	if value, err := p.Await(); err != nil || value != 1 {
+		t.Fatalf("Await = %d, %v; want the original 1, nil", value, err)
+	}
+	close(release)
+	step.Await()
+}
+
+func TestAllCancelableCancelsOnlyPending(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	done := NewCancelablePromise[int](context.Background(), func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+	})
+	pending := NewCancelablePromise[int](context.Background(), func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+		<-release
+		resolve(2)
+	})
+	// Keep the settled input's context held, as a running ChainCtx step would.
+	ChainCtx(done.Promise, func(ctx context.Context, value int) *Promise[int] {
+		return NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+			<-release
+			resolve(value)
+		})
+	})
+	done.Await()
+
+	all, cancel := AllCancelable(done, pending)
+	cancel()
+
+	if _, err := all.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Await error = %v, want context.Canceled", err)
+	}
+	if !errors.Is(context.Cause(pending.Context()), ErrCanceled) {
+		t.Fatalf("pending input cause = %v, want ErrCanceled", context.Cause(pending.Context()))
+	}
+	if err := done.Context().Err(); err != nil {
+		t.Fatalf("settled input's context was canceled: %v", err)
+	}
+}
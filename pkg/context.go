@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+)
+
+// contextLink owns the cancelable context of a promise created with
+// NewPromiseWithContext. The context is a child of the parent passed in, so it
+// stays registered with that parent until it is canceled. The link cancels it
+// once nothing holds it any more: the promise holds it until it has settled and
+// its handlers have run, and every ChainCtx step holds it until the step settles.
+type contextLink struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mutex sync.Mutex
+	holds int
+}
+
+// newContextLink derives a cancelable context from parent, held once by the caller.
+func newContextLink(parent context.Context) *contextLink {
+	ctx, cancel := context.WithCancelCause(parent)
+	return &contextLink{parent: parent, ctx: ctx, cancel: cancel, holds: 1}
+}
+
+// share returns the linked context and a function that drops the hold it takes.
+// If the link has already been released, it returns a new context derived from
+// the same parent instead, so a late step still follows the parent's cancellation.
+func (l *contextLink) share() (context.Context, func()) {
+	l.mutex.Lock()
+	if l.holds > 0 {
+		l.holds++
+		l.mutex.Unlock()
+		return l.ctx, l.release
+	}
+	l.mutex.Unlock()
+
+	ctx, cancel := context.WithCancelCause(l.parent)
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// release drops one hold, canceling the context once no holds remain, which
+// detaches it from its parent.
+func (l *contextLink) release() {
+	l.mutex.Lock()
+	l.holds--
+	last := l.holds == 0
+	l.mutex.Unlock()
+	if last {
+		l.cancel(context.Canceled)
+	}
+}
@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingContext counts the child contexts registered with it that have not
+// been released yet.
+type countingContext struct {
+	context.Context
+	active atomic.Int64
+}
+
+func (c *countingContext) AfterFunc(f func()) func() bool {
+	c.active.Add(1)
+	stop := context.AfterFunc(c.Context, f)
+	var once sync.Once
+	return func() bool {
+		once.Do(func() { c.active.Add(-1) })
+		return stop()
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSettledPromisesDetachFromParentContext(t *testing.T) {
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	parent := &countingContext{Context: base}
+
+	const n = 100
+	for i := range n {
+		p := NewPromiseWithContext[int](parent, func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+			resolve(i)
+		})
+		if i%2 == 0 {
+			p.Then(func(int) {})
+		}
+		p.Await()
+	}
+
+	waitFor(t, "settled promises to detach from the parent", func() bool {
+		return parent.active.Load() == 0
+	})
+	if base.Err() != nil {
+		t.Fatal("detaching promises canceled the parent")
+	}
+}
+
+func TestChainCtxAfterRootDetached(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	root := NewPromiseWithContext[int](ctx, func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+	})
+	root.Await()
+	waitFor(t, "the root context to be released", func() bool {
+		return root.Context().Err() != nil
+	})
+
+	// A step built on a released root still runs, and still follows ctx.
+	if value, err := ChainCtx(root, func(ctx context.Context, value int) *Promise[int] {
+		return Resolve(value + 1)
+	}).Await(); err != nil || value != 2 {
+		t.Fatalf("Await = %d, %v; want 2, nil", value, err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	step := ChainCtx(root, func(ctx context.Context, value int) *Promise[int] {
+		close(started)
+		return NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+			<-release
+			resolve(value)
+		})
+	})
+	<-started
+	cancel()
+	if _, err := step.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("step error = %v, want context.Canceled", err)
+	}
+}
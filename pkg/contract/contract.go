@@ -1,5 +1,14 @@
 package contract
 
+import (
+	"context"
+)
+
 // ExecutorFunc is the function passed to the promise, which performs the async operation.
 // It receives resolve and reject functions to signal completion or failure.
+// The finally function may be called by the executor to signal that its cleanup is
+// complete. It is idempotent, and calling it before resolve or reject rejects the promise.
 type ExecutorFunc[T any] func(resolve func(T), reject func(error), finally func())
+
+// ContextExecutorFunc is an ExecutorFunc that also receives the context the promise was created with.
+type ContextExecutorFunc[T any] func(ctx context.Context, resolve func(T), reject func(error), finally func())
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrExecutorFinished is the rejection reason of a promise whose executor called
+// its finally function without resolving or rejecting first.
+var ErrExecutorFinished = errors.New("promise: executor finished without settling")
+
+// ErrCanceled is the rejection reason of a promise canceled without a specific reason.
+// It matches context.Canceled with errors.Is.
+var ErrCanceled = fmt.Errorf("promise: canceled: %w", context.Canceled)
+
+// ErrAwaitDeadlock is returned by Await when it is called from the executor of the
+// promise being awaited, which would otherwise block forever. It is only detected
+// once enabled with SetDeadlockDetection.
+var ErrAwaitDeadlock = errors.New("promise: await called from the promise's own executor")
+
+// ErrAwaitAbandoned is returned by AwaitContext when the context ends before the
+// promise settles. It tells a caller that gave up waiting apart from a promise that failed.
+var ErrAwaitAbandoned = errors.New("promise: await abandoned")
+
+// ErrGroupClosed is the rejection reason of a promise created in a group that is
+// draining or has been drained by PromiseGroup.Drain.
+var ErrGroupClosed = errors.New("promise: group closed")
+
+// ErrTimeout is the rejection reason of a promise that gave up waiting after a deadline.
+var ErrTimeout = errors.New("promise: timed out")
+
+// IndexedError is the rejection reason of a combinator that identifies which of
+// its input promises failed.
+type IndexedError struct {
+	Index int
+	// Name is the name of the failing promise, if it was given one with Named.
+	Name string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *IndexedError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("promise %d (%s) rejected: %v", e.Index, e.Name, e.Err)
+	}
+	return fmt.Sprintf("promise %d rejected: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the rejection reason of the failing promise.
+func (e *IndexedError) Unwrap() error {
+	return e.Err
+}
+
+// AggregateError is the rejection reason of a combinator such as Any, FirstSuccess
+// or Hedge when every one of its inputs rejected. Errors holds their rejection
+// reasons in input order.
+type AggregateError struct {
+	Errors []error
+}
+
+// Error implements the error interface.
+func (e *AggregateError) Error() string {
+	if len(e.Errors) == 0 {
+		return "all promises rejected"
+	}
+	return fmt.Sprintf("all promises rejected: %v", e.Errors)
+}
+
+// Unwrap returns the contained errors, so errors.Is and errors.As look at each of them.
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// ByType groups the contained errors by the name of their concrete type, such as
+// "*net.OpError", for example to count how many attempts timed out.
+func (e *AggregateError) ByType() map[string][]error {
+	groups := make(map[string][]error)
+	for _, err := range e.Errors {
+		name := fmt.Sprintf("%T", err)
+		groups[name] = append(groups[name], err)
+	}
+	return groups
+}
+
+// First finds the first contained error that matches target according to
+// errors.As, sets target to it and reports true, or reports false if none matches.
+func (e *AggregateError) First(target any) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// PanicError is the rejection reason of a promise whose executor panicked
+// under the PanicRecover policy.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// newPanicError wraps a recovered panic value together with the current stack.
+func newPanicError(value any) *PanicError {
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("promise: panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
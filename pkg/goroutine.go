@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// activeGoroutines counts the goroutines started by the package that are running.
+var activeGoroutines atomic.Int64
+
+// ActiveGoroutines returns the number of goroutines started by the package that
+// have not returned yet: executors, handlers, settlement watchers and waiters such
+// as the one behind PromiseGroup.Drain. After all promises have completed it should
+// drop to zero; a value that stays above zero points at a leak.
+func ActiveGoroutines() int64 {
+	return activeGoroutines.Load()
+}
+
+// goTracked hands fn to the current Scheduler, which runs it on a new goroutine
+// by default. The task is counted by ActiveGoroutines until it returns.
+func goTracked(fn func()) {
+	activeGoroutines.Add(1)
+	currentScheduler().Go(func() {
+		defer activeGoroutines.Add(-1)
+		fn()
+	})
+}
+
+// goCounted runs fn on a new goroutine outside the Scheduler, counted by
+// ActiveGoroutines until it returns. It is for waiters that must make progress
+// even while the Scheduler is driven by the caller, such as a ManualScheduler.
+func goCounted(fn func()) {
+	activeGoroutines.Add(1)
+	go func() {
+		defer activeGoroutines.Add(-1)
+		fn()
+	}()
+}
+
+// goroutineID returns the id of the calling goroutine, parsed from the header
+// of its stack trace ("goroutine 42 [running]:"). It returns 0 if parsing fails.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
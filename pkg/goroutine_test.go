@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestActiveGoroutinesCountsWatchers(t *testing.T) {
+	WaitForPromises()
+	waitIdle(t)
+
+	g := NewPromiseGroup()
+	release := make(chan struct{})
+	NewPromiseIn[int](g, func(resolve func(int), reject func(error), finally func()) {
+		<-release
+		resolve(1)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.Drain(ctx); err == nil {
+		t.Fatal("Drain returned before the executor finished")
+	}
+	// The executor and the abandoned Drain waiter are both still running.
+	if n := ActiveGoroutines(); n < 2 {
+		t.Fatalf("ActiveGoroutines = %d, want at least 2", n)
+	}
+	close(release)
+	waitIdle(t)
+}
+
+// waitIdle waits for every goroutine started by the package to return.
+func waitIdle(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for ActiveGoroutines() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutine(s) still running", ActiveGoroutines())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,290 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PromiseGroup tracks a set of promises so that they can be waited on together
+// and share a common policy, such as a default error handler.
+// Promises are added to a group by creating them with NewPromiseIn.
+type PromiseGroup struct {
+	wg           sync.WaitGroup
+	mutex        sync.Mutex
+	defaultCatch func(error)
+
+	// tracking, members and edges record the promises of the group and how they
+	// were derived from one another, for ExportDOT.
+	tracking bool
+	members  []trackedPromise
+	edges    [][2]uint64
+
+	// closed is set by Drain; a closed group refuses new promises.
+	closed bool
+
+	// stagger is the minimum spacing between executor starts set by
+	// SetStartStagger, and nextStart the earliest time the next one may start.
+	// starts queues the executors waiting for a slot, which a single dispatcher
+	// goroutine launches in order while dispatching is set.
+	stagger     time.Duration
+	nextStart   time.Time
+	starts      []func()
+	dispatching bool
+}
+
+// promiseStatus is the state of a promise as reported for diagnostics.
+type promiseStatus int
+
+const (
+	statusPending promiseStatus = iota
+	statusFulfilled
+	statusRejected
+)
+
+// String returns the lower-case name of the state.
+func (s promiseStatus) String() string {
+	switch s {
+	case statusFulfilled:
+		return "fulfilled"
+	case statusRejected:
+		return "rejected"
+	default:
+		return "pending"
+	}
+}
+
+// trackedPromise is the type-erased view of a promise kept by a tracking group.
+type trackedPromise interface {
+	ID() uint64
+	Name() string
+	status() promiseStatus
+}
+
+// NewPromiseGroup creates and returns an empty PromiseGroup.
+func NewPromiseGroup() *PromiseGroup {
+	return &PromiseGroup{}
+}
+
+// Wait blocks until all promises in the group have completed.
+func (g *PromiseGroup) Wait() {
+	g.wg.Wait()
+}
+
+// SetStartStagger spaces out the start of executors in the group so that at most
+// one starts per interval d, which smooths the burst of load on backends when many
+// promises are created at once, such as at startup. Executors queue in creation
+// order and no goroutine is launched for one until its slot comes, so this trades
+// latency for reduced burst pressure: the n-th of a burst of promises starts about
+// n*d later. Only executors passed to NewPromiseIn are staggered; promises derived
+// from them, such as by ThenMap or Chain, start as soon as they are created.
+// The default of zero starts every executor straight away.
+func (g *PromiseGroup) SetStartStagger(d time.Duration) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.stagger = d
+}
+
+// start launches fn in the group's next start slot. Without a stagger, or when
+// the slot is already free and nothing is queued, fn is launched at once;
+// otherwise it is queued for the dispatcher.
+func (g *PromiseGroup) start(fn func()) {
+	g.mutex.Lock()
+	now := time.Now()
+	if g.stagger <= 0 || (len(g.starts) == 0 && !now.Before(g.nextStart)) {
+		if g.stagger > 0 {
+			g.nextStart = now.Add(g.stagger)
+		}
+		g.mutex.Unlock()
+		goTracked(fn)
+		return
+	}
+	g.starts = append(g.starts, fn)
+	dispatch := !g.dispatching
+	g.dispatching = true
+	g.mutex.Unlock()
+
+	// The dispatcher sleeps between slots, so it runs outside the Scheduler.
+	if dispatch {
+		goCounted(g.dispatchStarts)
+	}
+}
+
+// dispatchStarts launches the queued executors one per stagger interval and
+// returns once the queue is empty.
+func (g *PromiseGroup) dispatchStarts() {
+	for {
+		g.mutex.Lock()
+		if len(g.starts) == 0 {
+			g.dispatching = false
+			g.mutex.Unlock()
+			return
+		}
+		if wait := time.Until(g.nextStart); wait > 0 {
+			g.mutex.Unlock()
+			time.Sleep(wait)
+			continue
+		}
+		fn := g.starts[0]
+		g.starts[0] = nil
+		g.starts = g.starts[1:]
+		g.nextStart = time.Now().Add(g.stagger)
+		g.mutex.Unlock()
+		goTracked(fn)
+	}
+}
+
+// Drain gracefully shuts the group down: it stops accepting new promises and waits
+// for the promises already in it, and their handlers, to finish on their own. New
+// promises created in the group from then on are rejected with ErrGroupClosed without
+// running their executors. Drain returns nil once the group is idle, or
+// context.Cause(ctx) if ctx ends first; the group stays closed either way.
+func (g *PromiseGroup) Drain(ctx context.Context) error {
+	g.mutex.Lock()
+	g.closed = true
+	g.mutex.Unlock()
+
+	// The waiter runs outside the Scheduler, so draining makes progress even
+	// while a ManualScheduler is driven by the caller.
+	idle := make(chan struct{})
+	goCounted(func() {
+		g.wg.Wait()
+		close(idle)
+	})
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// admit counts a new promise in the group's wait group, unless the group has been
+// closed by Drain, in which case it reports false.
+func (g *PromiseGroup) admit() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.closed {
+		return false
+	}
+	g.wg.Add(1)
+	return true
+}
+
+// SetDefaultCatch sets an error handler shared by every promise in the group.
+// It is called when a promise rejects without an explicit Catch handler,
+// so rejections in the group are never dropped silently.
+// Passing nil removes the default handler.
+func (g *PromiseGroup) SetDefaultCatch(handler func(error)) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.defaultCatch = handler
+}
+
+// EnableTracking makes the group record every promise created in it from now on,
+// along with the edges between promises derived from one another, so that the
+// group can be exported with ExportDOT. Tracked promises are retained by the
+// group, so tracking is meant for debugging.
+func (g *PromiseGroup) EnableTracking() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.tracking = true
+}
+
+// ExportDOT returns a Graphviz DOT representation of the tracked promises of the group.
+// Every promise is a node labeled with its ID and name and colored by its state, and every derivation, such as the
+// promise returned by Replace or MapError, is an edge from the parent to the child.
+func (g *PromiseGroup) ExportDOT() string {
+	g.mutex.Lock()
+	members := append([]trackedPromise(nil), g.members...)
+	edges := append([][2]uint64(nil), g.edges...)
+	g.mutex.Unlock()
+
+	var b strings.Builder
+	b.WriteString("digraph promises {\n")
+	b.WriteString("\tnode [shape=box, style=filled];\n")
+	for _, m := range members {
+		label := fmt.Sprintf("#%d", m.ID())
+		if name := m.Name(); name != "" {
+			label += " " + name
+		}
+		fmt.Fprintf(&b, "\tp%d [label=%q, fillcolor=%q];\n", m.ID(), label, statusColor(m.status()))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\tp%d -> p%d;\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// statusColor returns the DOT fill color used for a promise state.
+func statusColor(status promiseStatus) string {
+	switch status {
+	case statusFulfilled:
+		return "palegreen"
+	case statusRejected:
+		return "lightcoral"
+	default:
+		return "lightgray"
+	}
+}
+
+// track records a newly created promise if the group is tracking.
+func (g *PromiseGroup) track(p trackedPromise) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.tracking {
+		g.members = append(g.members, p)
+	}
+}
+
+// addEdge records that the promise child was derived from the promise parent.
+func (g *PromiseGroup) addEdge(parent, child uint64) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.tracking {
+		g.edges = append(g.edges, [2]uint64{parent, child})
+	}
+}
+
+// defaultCatchHandler returns the group's current default error handler, if any.
+func (g *PromiseGroup) defaultCatchHandler() func(error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.defaultCatch
+}
+
+// spawn runs a handler on a new goroutine tracked by the group's wait group,
+// such as a handler attached after a promise has already settled.
+func (g *PromiseGroup) spawn(handler func()) {
+	g.wg.Add(1)
+	goTracked(func() {
+		defer g.wg.Done()
+		defer g.recoverHandler()
+		handler()
+	})
+}
+
+// protect runs handler under recoverHandler, so that a handler panicking under
+// the PanicRecover policy does not stop the handlers that run after it.
+func (g *PromiseGroup) protect(handler func()) {
+	defer g.recoverHandler()
+	handler()
+}
+
+// recoverHandler must be deferred by goroutines running handlers. Under the
+// PanicRecover policy it recovers a panicking handler and passes the panic to
+// the group's default catch handler, if one is set.
+func (g *PromiseGroup) recoverHandler() {
+	if panicPolicy.Load() != int32(PanicRecover) {
+		return
+	}
+	if r := recover(); r != nil {
+		if handler := g.defaultCatchHandler(); handler != nil {
+			handler(newPanicError(r))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package pkg
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartStaggerSpacesUserExecutors(t *testing.T) {
+	const stagger = 20 * time.Millisecond
+	g := NewPromiseGroup()
+	g.SetStartStagger(stagger)
+
+	var mutex sync.Mutex
+	var starts []time.Time
+	for range 3 {
+		NewPromiseIn[int](g, func(resolve func(int), reject func(error), finally func()) {
+			mutex.Lock()
+			starts = append(starts, time.Now())
+			mutex.Unlock()
+			resolve(1)
+		})
+	}
+	g.Wait()
+
+	for i := 1; i < len(starts); i++ {
+		// Allow for timer granularity.
+		if gap := starts[i].Sub(starts[i-1]); gap < stagger*9/10 {
+			t.Fatalf("executor %d started %v after the previous one, want at least %v", i, gap, stagger)
+		}
+	}
+}
+
+func TestStartStaggerQueuesWithoutGoroutines(t *testing.T) {
+	g := NewPromiseGroup()
+	g.SetStartStagger(50 * time.Millisecond)
+
+	before := ActiveGoroutines()
+	for range 10 {
+		NewPromiseIn[int](g, func(resolve func(int), reject func(error), finally func()) {
+			resolve(1)
+		})
+	}
+	// At most the first executor and the dispatcher are running; the other
+	// nine wait in the queue without a goroutine each.
+	if n := ActiveGoroutines() - before; n > 2 {
+		t.Fatalf("%d goroutines running for 10 staggered promises, want at most 2", n)
+	}
+	g.Wait()
+}
+
+func TestStartStaggerSkipsDerivedPromises(t *testing.T) {
+	g := NewPromiseGroup()
+	g.SetStartStagger(50 * time.Millisecond)
+
+	started := time.Now()
+	root := NewPromiseIn[int](g, func(resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+	})
+	step := func(v int) (int, error) { return v + 1, nil }
+	value, err := ThenMap(ThenMap(ThenMap(root, step), step), step).Await()
+	if err != nil || value != 4 {
+		t.Fatalf("Await = %d, %v; want 4, nil", value, err)
+	}
+	if elapsed := time.Since(started); elapsed > 40*time.Millisecond {
+		t.Fatalf("a 3-step ThenMap chain took %v, want it not to wait for start slots", elapsed)
+	}
+}
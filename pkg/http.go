@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Do sends req with http.DefaultClient and returns a promise that resolves with
+// the response or rejects with the transport error. The request is bound to ctx,
+// so cancelling ctx aborts it. The caller must close the response body.
+func Do(ctx context.Context, req *http.Request) *Promise[*http.Response] {
+	return Promisify(func() (*http.Response, error) {
+		return http.DefaultClient.Do(req.WithContext(ctx))
+	})
+}
+
+// Get issues a GET request to url and returns a promise for the response.
+// The caller must close the response body.
+func Get(ctx context.Context, url string) *Promise[*http.Response] {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Promisify(func() (*http.Response, error) {
+			return nil, err
+		})
+	}
+	return Do(ctx, req)
+}
+
+// AwaitJSON waits for the response promise p and decodes its JSON body into out.
+// It returns the rejection error of p, an error for a non-2xx status code, or the
+// decoding error. The response body is always closed.
+func AwaitJSON[T any](p *Promise[*http.Response], out *T) error {
+	resp, err := p.Await()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// promiseResultJSON is the wire format of a PromiseResult.
+type promiseResultJSON[T any] struct {
+	Fulfilled bool   `json:"fulfilled"`
+	Value     *T     `json:"value,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the result as {"fulfilled":true,"value":...} or
+// {"fulfilled":false,"error":"..."}. Errors are not JSON-serializable,
+// so the error is encoded as its message.
+func (r PromiseResult[T]) MarshalJSON() ([]byte, error) {
+	out := promiseResultJSON[T]{Fulfilled: r.Fulfilled}
+	if r.Fulfilled {
+		out.Value = &r.Value
+	} else if r.Error != nil {
+		out.Error = r.Error.Error()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON. The error of a rejected
+// result is restored as a plain error carrying the original message.
+func (r *PromiseResult[T]) UnmarshalJSON(data []byte) error {
+	var in promiseResultJSON[T]
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*r = PromiseResult[T]{Fulfilled: in.Fulfilled}
+	if in.Value != nil {
+		r.Value = *in.Value
+	}
+	if !in.Fulfilled {
+		r.Error = errors.New(in.Error)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"cmp"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PromiseInfo describes a pending promise tracked by leak detection.
+type PromiseInfo struct {
+	ID      uint64
+	Name    string
+	Created time.Time
+	Age     time.Duration
+	Stack   string
+}
+
+// leakDetector records every promise created while leak detection is enabled
+// until it settles.
+var leakDetector struct {
+	enabled   atomic.Bool
+	mutex     sync.Mutex
+	threshold time.Duration
+	pending   map[uint64]PromiseInfo
+}
+
+// EnableLeakDetection starts recording the creation time and stack of every new promise
+// until it settles. LeakReport then lists the promises that have been pending longer than
+// threshold. Tracking captures a stack trace per promise, so it is meant for debugging.
+func EnableLeakDetection(threshold time.Duration) {
+	leakDetector.mutex.Lock()
+	defer leakDetector.mutex.Unlock()
+	leakDetector.threshold = threshold
+	if leakDetector.pending == nil {
+		leakDetector.pending = make(map[uint64]PromiseInfo)
+	}
+	leakDetector.enabled.Store(true)
+}
+
+// DisableLeakDetection stops tracking new promises and forgets the ones already tracked.
+func DisableLeakDetection() {
+	leakDetector.mutex.Lock()
+	defer leakDetector.mutex.Unlock()
+	leakDetector.enabled.Store(false)
+	leakDetector.pending = nil
+}
+
+// LeakReport returns the tracked promises that are still pending after the threshold
+// passed to EnableLeakDetection, ordered by ID.
+func LeakReport() []PromiseInfo {
+	leakDetector.mutex.Lock()
+	defer leakDetector.mutex.Unlock()
+
+	now := time.Now()
+	var report []PromiseInfo
+	for _, info := range leakDetector.pending {
+		info.Age = now.Sub(info.Created)
+		if info.Age >= leakDetector.threshold {
+			report = append(report, info)
+		}
+	}
+	slices.SortFunc(report, func(a, b PromiseInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return report
+}
+
+// trackPending records a newly created promise if leak detection is enabled.
+// It reports whether the promise is being tracked.
+func trackPending(id uint64) bool {
+	if !leakDetector.enabled.Load() {
+		return false
+	}
+	info := PromiseInfo{ID: id, Created: time.Now(), Stack: string(debug.Stack())}
+
+	leakDetector.mutex.Lock()
+	defer leakDetector.mutex.Unlock()
+	if leakDetector.pending == nil {
+		return false
+	}
+	leakDetector.pending[id] = info
+	return true
+}
+
+// untrackPending forgets a promise once it has settled.
+func untrackPending(id uint64) {
+	leakDetector.mutex.Lock()
+	defer leakDetector.mutex.Unlock()
+	delete(leakDetector.pending, id)
+}
+
+// renamePending updates the name recorded for a tracked promise.
+func renamePending(id uint64, name string) {
+	leakDetector.mutex.Lock()
+	defer leakDetector.mutex.Unlock()
+	if info, ok := leakDetector.pending[id]; ok {
+		info.Name = name
+		leakDetector.pending[id] = info
+	}
+}
@@ -0,0 +1,86 @@
+package pkg
+
+// AllAdaptive runs the promises created by factories with a concurrency limit that
+// adapts to the backend, in the style of AIMD congestion control. The limit starts at
+// initial, grows by one after a full window of consecutive successes and is halved
+// on every rejection, always staying between minLimit and maxLimit.
+// Unlike All, a rejection does not stop the remaining factories from running.
+// It resolves with all values in factory order once every promise has fulfilled,
+// or rejects with the first error in factory order.
+func AllAdaptive[T any](initial, minLimit, maxLimit int, factories []func() *Promise[T]) *Promise[[]T] {
+	return NewPromise[[]T](func(resolve func([]T), reject func(error), finally func()) {
+		minLimit = max(minLimit, 1)
+		maxLimit = max(maxLimit, minLimit)
+		limit := min(max(initial, minLimit), maxLimit)
+
+		results := make([]T, len(factories))
+		errors := make([]error, len(factories))
+		settled := make(chan error, len(factories))
+
+		next, inFlight, streak := 0, 0, 0
+		for next < len(factories) || inFlight > 0 {
+			for next < len(factories) && inFlight < limit {
+				idx := next
+				p := factories[idx]()
+				next++
+				inFlight++
+				p.whenSettled(func(value T, err error) {
+					results[idx], errors[idx] = value, err
+					settled <- err
+				})
+			}
+
+			err := <-settled
+			inFlight--
+			if err != nil {
+				// Multiplicative decrease on failure.
+				limit = max(minLimit, limit/2)
+				streak = 0
+				continue
+			}
+			// Additive increase once a full window has succeeded.
+			streak++
+			if streak >= limit {
+				limit = min(maxLimit, limit+1)
+				streak = 0
+			}
+		}
+
+		for _, err := range errors {
+			if err != nil {
+				reject(err)
+				return
+			}
+		}
+		resolve(results)
+	})
+}
+
+// AllSettledWithLimit runs the promises created by factories with at most limit of
+// them in flight at once, and resolves with the settlement of every promise in
+// factory order, like AllSettled. It never rejects. A limit below one is treated as one.
+func AllSettledWithLimit[T any](limit int, factories []func() *Promise[T]) *Promise[[]PromiseResult[T]] {
+	return NewPromise[[]PromiseResult[T]](func(resolve func([]PromiseResult[T]), reject func(error), finally func()) {
+		limit = max(limit, 1)
+		results := make([]PromiseResult[T], len(factories))
+		settled := make(chan struct{}, len(factories))
+
+		next, inFlight := 0, 0
+		for next < len(factories) || inFlight > 0 {
+			for next < len(factories) && inFlight < limit {
+				idx := next
+				p := factories[idx]()
+				next++
+				inFlight++
+				p.whenSettled(func(value T, err error) {
+					results[idx] = PromiseResult[T]{Value: value, Error: err, Fulfilled: err == nil}
+					settled <- struct{}{}
+				})
+			}
+
+			<-settled
+			inFlight--
+		}
+		resolve(results)
+	})
+}
@@ -0,0 +1,17 @@
+package pkg
+
+import "promise/pkg/contract"
+
+// Middleware wraps an executor to add a cross-cutting concern such as logging,
+// metrics or tracing. It returns a new executor that typically calls the one it wraps.
+type Middleware[T any] func(contract.ExecutorFunc[T]) contract.ExecutorFunc[T]
+
+// NewPromiseWithMiddleware creates a promise whose executor is wrapped by mws.
+// Middlewares apply in order: the first one is the outermost and sees the call
+// before any other, the last one wraps executor directly.
+func NewPromiseWithMiddleware[T any](executor contract.ExecutorFunc[T], mws ...Middleware[T]) *Promise[T] {
+	for i := len(mws) - 1; i >= 0; i-- {
+		executor = mws[i](executor)
+	}
+	return NewPromise(executor)
+}
@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+)
+
+// AsyncMutex is a mutual exclusion lock acquired through a promise, so async code
+// can serialize access to a resource without parking a goroutine on the lock.
+// Waiters acquire the lock in the order they called Lock.
+type AsyncMutex struct {
+	mutex   sync.Mutex
+	locked  bool
+	waiters []*Promise[func()]
+}
+
+// NewAsyncMutex creates and returns an unlocked AsyncMutex.
+func NewAsyncMutex() *AsyncMutex {
+	return &AsyncMutex{}
+}
+
+// Lock returns a promise that fulfills with an unlock function once the lock has
+// been acquired. The holder must call the unlock function exactly once when done;
+// further calls have no effect. No goroutine is blocked while the promise is pending.
+func (m *AsyncMutex) Lock() *Promise[func()] {
+	p := newPromise[func()](defaultGroup, context.Background())
+	if p.refused {
+		return p
+	}
+
+	m.mutex.Lock()
+	if m.locked {
+		m.waiters = append(m.waiters, p)
+		m.mutex.Unlock()
+		return p
+	}
+	m.locked = true
+	m.mutex.Unlock()
+
+	p.settle(m.unlocker(), nil)
+	return p
+}
+
+// unlocker returns an idempotent function that releases the lock once.
+func (m *AsyncMutex) unlocker() func() {
+	var once sync.Once
+	return func() {
+		once.Do(m.handOff)
+	}
+}
+
+// handOff passes the lock to the oldest waiter, or unlocks the mutex if none is waiting.
+func (m *AsyncMutex) handOff() {
+	m.mutex.Lock()
+	if len(m.waiters) == 0 {
+		m.locked = false
+		m.mutex.Unlock()
+		return
+	}
+	next := m.waiters[0]
+	m.waiters[0] = nil
+	m.waiters = m.waiters[1:]
+	m.mutex.Unlock()
+
+	next.settle(m.unlocker(), nil)
+}
@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// defaultInlineYieldInterval is the number of inline handlers run between yields
+// to the scheduler unless configured otherwise.
+const defaultInlineYieldInterval = 64
+
+// PanicPolicy controls what happens when an executor or handler panics.
+type PanicPolicy int32
+
+const (
+	// PanicRecover recovers panics. A panicking executor rejects its promise with a
+	// *PanicError, and a panicking handler is reported to its group's default catch
+	// handler. This is the default.
+	PanicRecover PanicPolicy = iota
+	// PanicRepanic lets panics propagate and crash the program with a full stack trace.
+	// A panicking executor still rejects its promise before the panic propagates,
+	// so goroutines blocked in Await are released.
+	PanicRepanic
+)
+
+var (
+	panicPolicy         atomic.Int32
+	runHandlersInline   atomic.Bool
+	inlineYieldInterval atomic.Int64
+	inlineHandlerCount  atomic.Int64
+	deadlockDetection   atomic.Bool
+)
+
+func init() {
+	inlineYieldInterval.Store(defaultInlineYieldInterval)
+}
+
+// SetRunHandlersInline controls whether Then/Catch/Finally handlers run on the
+// goroutine that settles the promise instead of on a new goroutine.
+// Running inline avoids a goroutine per handler, but a slow handler then delays
+// the executor that settled the promise. It is disabled by default.
+func SetRunHandlersInline(enabled bool) {
+	runHandlersInline.Store(enabled)
+}
+
+// SetInlineYieldInterval sets how many handlers run inline before the settling
+// goroutine yields to the scheduler with runtime.Gosched. This keeps a long chain
+// of synchronously settling promises from monopolising a processor.
+// A value of zero or less disables yielding. The default is 64.
+func SetInlineYieldInterval(n int) {
+	inlineYieldInterval.Store(int64(n))
+}
+
+// SetDeadlockDetection controls whether Await detects being called from the
+// executor of the promise it waits on and returns ErrAwaitDeadlock instead of
+// blocking forever. Detection tags every executor goroutine with its id, which
+// costs about as much as creating a promise, so it is meant for debugging and
+// tests and is disabled by default.
+func SetDeadlockDetection(enabled bool) {
+	deadlockDetection.Store(enabled)
+}
+
+// SetPanicPolicy sets how panics in executors and handlers are treated.
+// Use PanicRecover for robustness and PanicRepanic to fail fast while debugging.
+func SetPanicPolicy(policy PanicPolicy) {
+	panicPolicy.Store(int32(policy))
+}
+
+// runHandler runs a settlement handler, either on a new goroutine or, when inline
+// mode is enabled, on the calling goroutine with a periodic yield for fairness.
+func runHandler(handler func()) {
+	if !runHandlersInline.Load() {
+		goTracked(handler)
+		return
+	}
+
+	handler()
+	if n := inlineYieldInterval.Load(); n > 0 && inlineHandlerCount.Add(1)%n == 0 {
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"sync"
+)
+
+// ProgressPolicy decides what happens to a progress update when the buffer is full.
+type ProgressPolicy int
+
+const (
+	// ProgressDropOldest discards the oldest buffered update to make room for the
+	// new one, so the latest progress always wins. This is the default.
+	ProgressDropOldest ProgressPolicy = iota
+	// ProgressDropNewest discards the incoming update and keeps the buffered ones.
+	ProgressDropNewest
+)
+
+// ProgressOptions configures how progress updates are delivered to consumers.
+type ProgressOptions struct {
+	// Buffer is the number of updates held for a slow consumer. Values below 1 use 1.
+	Buffer int
+	// Policy selects which update is dropped when the buffer is full.
+	Policy ProgressPolicy
+}
+
+// ProgressPromise is a Promise whose executor can report intermediate progress.
+// Progress is delivered through a buffered channel, so a slow consumer never
+// blocks the executor; updates are dropped according to the configured policy instead.
+type ProgressPromise[T, P any] struct {
+	*Promise[T]
+
+	mutex   sync.Mutex
+	policy  ProgressPolicy
+	updates chan P
+	closed  bool
+}
+
+// NewProgressPromise creates a promise whose executor receives a progress function
+// in addition to resolve and reject. The progress channel is closed once the
+// promise settles, after any buffered updates have been read.
+func NewProgressPromise[T, P any](opts ProgressOptions, executor func(resolve func(T), reject func(error), progress func(P))) *ProgressPromise[T, P] {
+	pp := &ProgressPromise[T, P]{
+		policy:  opts.Policy,
+		updates: make(chan P, max(opts.Buffer, 1)),
+	}
+	pp.Promise = NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
+		executor(resolve, reject, pp.report)
+	})
+
+	pp.onSettled(func() {
+		pp.mutex.Lock()
+		defer pp.mutex.Unlock()
+		pp.closed = true
+		close(pp.updates)
+	})
+
+	return pp
+}
+
+// Progress returns the channel on which progress updates are delivered.
+func (pp *ProgressPromise[T, P]) Progress() <-chan P {
+	return pp.updates
+}
+
+// OnProgress registers a handler that is called for every delivered progress update.
+// It returns the promise itself to allow for chaining.
+func (pp *ProgressPromise[T, P]) OnProgress(handler func(P)) *ProgressPromise[T, P] {
+	pp.group.spawn(func() {
+		for update := range pp.updates {
+			handler(update)
+		}
+	})
+	return pp
+}
+
+// report delivers a progress update without ever blocking the executor.
+func (pp *ProgressPromise[T, P]) report(update P) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+	if pp.closed {
+		return
+	}
+
+	select {
+	case pp.updates <- update:
+		return
+	default:
+	}
+
+	if pp.policy == ProgressDropNewest {
+		return
+	}
+
+	// Make room by discarding the oldest update. Only report sends on the
+	// channel and it holds the mutex, so the send below cannot block.
+	select {
+	case <-pp.updates:
+	default:
+	}
+	pp.updates <- update
+}
@@ -0,0 +1,35 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressSlowConsumerDoesNotBlockExecutor(t *testing.T) {
+	const updates = 1000
+	release := make(chan struct{})
+	p := NewProgressPromise[int, int](ProgressOptions{Buffer: 1}, func(resolve func(int), reject func(error), progress func(int)) {
+		for i := range updates {
+			progress(i)
+		}
+		resolve(updates)
+	})
+	// The consumer blocks on its first update until the executor has finished.
+	var received []int
+	p.OnProgress(func(update int) {
+		<-release
+		received = append(received, update)
+	})
+
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		t.Fatal("executor was blocked by a slow progress consumer")
+	}
+	close(release)
+	WaitForPromises()
+
+	if len(received) == 0 || received[len(received)-1] != updates-1 {
+		t.Fatalf("received %v, want the latest update %d last", received, updates-1)
+	}
+}
@@ -1,7 +1,10 @@
 package pkg
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"promise/pkg/contract"
 )
@@ -11,109 +14,662 @@ import (
 // It uses a sync.Mutex to handle concurrent access to its handlers,
 // making it safe for cases where .Then or .Catch might be called after resolution.
 type Promise[T any] struct {
-	mutex   sync.Mutex
-	then    func(T)
-	catch   func(error)
-	finally func()
+	mutex    sync.Mutex
+	then     func(T)
+	catch    func(error)
+	finally  []func()
+	caught   bool
+	detached bool
+	syncMode bool
+	group    *PromiseGroup
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	link     *contextLink
+	id       uint64
+	tracked  bool
+	name     string
+
+	// lazyStart starts the executor of a promise created with Lazy.
+	lazyStart func()
+	lazyOnce  sync.Once
+
+	// onceThen holds the handlers registered by OnceThen that have not run yet,
+	// and onceKeys the key of every handler OnceThen has registered.
+	onceThen []func(T)
+	onceKeys map[string]struct{}
+
+	// refused is set at creation if the group was closed by Drain.
+	refused bool
+
+	// clone, if set, copies the value for each consumer so that none of them
+	// can modify what the others see. It is set before the executor starts.
+	clone func(T) T
+
+	// finallyTail is closed once every Finally handler registered so far has
+	// returned. It is set when the promise settles, and each Finally handler
+	// registered afterwards waits on it and replaces it with its own.
+	finallyTail chan struct{}
+
+	// subscribers are run through the Scheduler once the promise settles,
+	// so that internal watchers never park a goroutine on a pending promise.
+	subscribers []func()
+
+	// executorGoroutine is the id of the goroutine running the executor, or 0.
+	executorGoroutine atomic.Uint64
+
+	// done is closed once the promise settles. value and err are written
+	// before done is closed, so they may be read without the mutex afterwards.
+	done    chan struct{}
+	settled bool
+	value   T
+	err     error
 }
 
 // NewPromise creates and returns a new Promise.
 // It takes an executor function that will be run in a separate goroutine.
+// The promise belongs to the default group waited on by WaitForPromises.
 func NewPromise[T any](executor contract.ExecutorFunc[T]) *Promise[T] {
-	p := &Promise[T]{}
-	wg.Add(1)
+	return NewPromiseIn(defaultGroup, executor)
+}
+
+// NewPromiseIn creates and returns a new Promise that belongs to group g.
+// It takes an executor function that will be run in a separate goroutine.
+func NewPromiseIn[T any](g *PromiseGroup, executor contract.ExecutorFunc[T]) *Promise[T] {
+	p := newPromise[T](g, context.Background())
+	p.runStaggered(executor)
+	return p
+}
+
+// NewPromiseWithContext creates and returns a new Promise bound to ctx.
+// The executor receives ctx so it can read request-scoped values and stop early,
+// and the same context is available to handlers through Context.
+// If ctx is done before the promise settles, the promise rejects with context.Cause(ctx).
+// Such promises are cancelable, which lets combinators like AllCancel stop them early.
+// Once the promise has settled and its handlers have run, its context is canceled
+// to detach it from ctx, so a long-lived ctx does not keep settled promises reachable.
+func NewPromiseWithContext[T any](ctx context.Context, executor contract.ContextExecutorFunc[T]) *Promise[T] {
+	link := newContextLink(ctx)
+	ctx = link.ctx
+	p := newPromise[T](defaultGroup, ctx)
+	p.cancel = link.cancel
+	p.link = link
+	stop := context.AfterFunc(ctx, func() {
+		p.reject(context.Cause(ctx))
+	})
+	p.onSettled(func() { stop() })
+	p.run(func(resolve func(T), reject func(error), finally func()) {
+		executor(ctx, resolve, reject, finally)
+	})
+	return p
+}
+
+// nextPromiseID is the source of the unique ID assigned to every promise.
+var nextPromiseID atomic.Uint64
+
+// newPromise creates a pending promise in group g without starting any work.
+// If g has been closed by Drain, the promise is refused: it is rejected with
+// ErrGroupClosed straight away and its executor never runs.
+func newPromise[T any](g *PromiseGroup, ctx context.Context) *Promise[T] {
+	admitted := g.admit()
+	id := nextPromiseID.Add(1)
+	p := &Promise[T]{done: make(chan struct{}), group: g, ctx: ctx, id: id, tracked: trackPending(id)}
+	g.track(p)
+	if !admitted {
+		p.refused = true
+		p.detached = true
+		p.reject(ErrGroupClosed)
+	}
+	return p
+}
+
+// derive creates a promise in the same group as parent and starts its executor,
+// recording the parent→child edge for ExportDOT.
+func derive[T, R any](parent *Promise[T], executor contract.ExecutorFunc[R]) *Promise[R] {
+	child := newPromise[R](parent.group, context.Background())
+	parent.group.addEdge(parent.id, child.id)
+	child.run(executor)
+	return child
+}
+
+// NewPromiseSync creates a promise whose executor runs on the calling goroutine,
+// so a synchronous executor has settled the promise by the time NewPromiseSync
+// returns. Handlers attached after settlement run inline on the goroutine that
+// attaches them instead of on a new goroutine, which avoids scheduling overhead
+// for the common already-settled case and runs them in attachment order.
+func NewPromiseSync[T any](executor contract.ExecutorFunc[T]) *Promise[T] {
+	p := newPromise[T](defaultGroup, context.Background())
+	p.syncMode = true
+	p.execute(executor)
+	return p
+}
+
+// Resolve returns a promise that has already fulfilled with value. Like a
+// NewPromiseSync promise, handlers attached to it run on the attaching goroutine
+// and have returned before Then returns, which keeps tests deterministic without
+// a call to WaitForPromises.
+func Resolve[T any](value T) *Promise[T] {
+	return NewPromiseSync[T](func(resolve func(T), reject func(error), finally func()) {
+		resolve(value)
+	})
+}
+
+// Reject returns a promise that has already rejected with err. Handlers attached
+// to it run on the attaching goroutine, as with Resolve. Because the promise
+// rejects before a Catch handler can be attached, the group's default catch
+// handler, if one is set, also sees err.
+func Reject[T any](err error) *Promise[T] {
+	return NewPromiseSync[T](func(resolve func(T), reject func(error), finally func()) {
+		reject(err)
+	})
+}
+
+// Lazy creates a promise that does not run factory until it is first consumed:
+// when a Then, Catch or Finally handler is attached, or it is awaited through
+// Await, Done or a combinator. factory runs at most once. Until then the promise
+// does not keep WaitForPromises waiting, so unused lazy promises cost nothing.
+func Lazy[T any](factory func() (T, error)) *Promise[T] {
+	p := newPromise[T](defaultGroup, context.Background())
+	p.Detach()
+	p.lazyStart = func() {
+		p.mutex.Lock()
+		if p.settled {
+			p.mutex.Unlock()
+			return
+		}
+		if !p.group.admit() {
+			p.mutex.Unlock()
+			p.reject(ErrGroupClosed)
+			return
+		}
+		p.detached = false
+		p.mutex.Unlock()
+
+		p.runStaggered(func(resolve func(T), reject func(error), finally func()) {
+			value, err := factory()
+			if err != nil {
+				reject(err)
+				return
+			}
+			resolve(value)
+		})
+	}
+	return p
+}
+
+// run starts the executor of the promise in a separate goroutine.
+// The core of the async operation. We run the executor in a new goroutine
+// so that the NewPromise call doesn't block.
+func (p *Promise[T]) run(executor contract.ExecutorFunc[T]) {
+	if p.refused {
+		return
+	}
+	goTracked(func() {
+		p.execute(executor)
+	})
+}
+
+// runStaggered is run for executors supplied by the user, which wait for the
+// group's next start slot if the group staggers starts.
+func (p *Promise[T]) runStaggered(executor contract.ExecutorFunc[T]) {
+	if p.refused {
+		return
+	}
+	p.group.start(func() {
+		p.execute(executor)
+	})
+}
+
+// execute runs the executor of the promise on the calling goroutine.
+// The executor of a promise refused by a closed group is skipped.
+func (p *Promise[T]) execute(executor contract.ExecutorFunc[T]) {
+	if p.refused {
+		return
+	}
 
 	// The resolve function handles the successful completion of the promise.
 	resolve := func(value T) {
-		p.mutex.Lock()
-		defer p.mutex.Unlock()
-		if p.then != nil {
-			// We launch the handler in a new goroutine to avoid blocking the
-			// original executor goroutine if the .Then handler is slow.
-			go func() {
-				p.then(value)
-				wg.Done()
-			}()
+		p.settle(value, nil)
+	}
+
+	// The finally function lets the executor signal that it has finished its
+	// cleanup. It is idempotent and does not touch the wait group; if the
+	// executor finishes without settling, the promise is rejected so that it
+	// doesn't stay pending forever.
+	var finallyOnce sync.Once
+	finally := func() {
+		finallyOnce.Do(func() {
+			p.reject(ErrExecutorFinished)
+		})
+	}
+
+	// With deadlock detection enabled, tag the goroutine so that Await can
+	// detect being called from the executor of the very promise it waits on.
+	if deadlockDetection.Load() {
+		p.executorGoroutine.Store(goroutineID())
+		defer p.executorGoroutine.Store(0)
+	}
+
+	// A panicking executor rejects the promise. Under the PanicRecover policy
+	// that is the end of it; under PanicRepanic the panic is raised again once
+	// the promise has settled, so Await and Done are released before the
+	// program crashes instead of hanging forever.
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		p.reject(newPanicError(r))
+		if panicPolicy.Load() == int32(PanicRepanic) {
+			panic(r)
 		}
+	}()
+	executor(resolve, p.reject, finally)
+}
+
+// cancelWith cancels the context of a cancelable promise with the given cause.
+// It has no effect on promises that are not cancelable or have already settled,
+// whose context is left for handlers to use. The check and the cancellation are
+// made under the mutex; canceling a context never runs callbacks synchronously,
+// so nothing can call back into the promise while the mutex is held.
+func (p *Promise[T]) cancelWith(cause error) {
+	if p.cancel == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.settled {
+		p.cancel(cause)
 	}
+}
 
-	// The reject function handles the failure of the promise.
-	reject := func(err error) {
-		p.mutex.Lock()
-		defer p.mutex.Unlock()
-		if p.catch != nil {
-			// Same as resolve, run handler in a new goroutine.
-			go func() {
-				p.catch(err)
-				wg.Done()
-			}()
+// reject handles the failure of the promise.
+func (p *Promise[T]) reject(err error) {
+	if err == nil {
+		err = fmt.Errorf("promise rejected with nil error")
+	}
+	var zero T
+	p.settle(zero, err)
+}
+
+// Promisify runs fn in a separate goroutine and returns a promise that resolves
+// with its value or rejects with its error.
+func Promisify[T any](fn func() (T, error)) *Promise[T] {
+	return NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
+		value, err := fn()
+		if err != nil {
+			reject(err)
+			return
 		}
+		resolve(value)
+	})
+}
+
+// settle records the outcome of the promise and runs the registered handler.
+// Only the first call has any effect; later calls are ignored. The settled flag
+// is checked and set under the mutex, so value and err are written exactly once
+// and every accessor reads the same cached outcome after done is closed.
+func (p *Promise[T]) settle(value T, err error) {
+	p.mutex.Lock()
+	if p.settled {
+		p.mutex.Unlock()
+		return
+	}
+	p.settled = true
+	p.value = value
+	p.err = err
+	close(p.done)
+	if p.tracked {
+		untrackPending(p.id)
 	}
 
-	finally := func() {
-		p.mutex.Lock()
-		defer p.mutex.Unlock()
-		if p.finally != nil {
-			go func() {
-				p.finally()
-				wg.Done()
-			}()
+	then, catch, finally := p.then, p.catch, p.finally
+	onceThen := p.onceThen
+	p.onceThen = nil
+	tail := make(chan struct{})
+	p.finallyTail = tail
+	subscribers := p.subscribers
+	p.subscribers = nil
+	var defaultCatch func(error)
+	if err != nil && !p.caught {
+		defaultCatch = p.group.defaultCatchHandler()
+	}
+	// A detached promise has already released its slot in the wait group.
+	release := p.group.wg.Done
+	if p.detached {
+		release = func() {}
+	}
+	// Once the handlers have run, the context can be released from its parent.
+	if link := p.link; link != nil {
+		releaseGroup := release
+		release = func() {
+			link.release()
+			releaseGroup()
 		}
 	}
+	p.mutex.Unlock()
 
-	// The core of the async operation. We run the executor in a new goroutine
-	// so that the NewPromise call doesn't block.
-	go executor(resolve, reject, finally)
+	for _, subscriber := range subscribers {
+		goTracked(subscriber)
+	}
 
-	return p
+	if len(finally) == 0 && ((err == nil && then == nil && len(onceThen) == 0) || (err != nil && catch == nil && defaultCatch == nil)) {
+		close(tail)
+		release()
+		return
+	}
+
+	// We launch the handler in a new goroutine to avoid blocking the
+	// original executor goroutine if the handler is slow, unless handlers
+	// are configured to run inline.
+	// Each handler is protected on its own, so one that panics under
+	// PanicRecover does not keep the handlers after it from running.
+	runHandler(func() {
+		defer release()
+		defer close(tail)
+		g := p.group
+		if err == nil {
+			if then != nil {
+				g.protect(func() { then(p.view(value)) })
+			}
+			for _, handler := range onceThen {
+				g.protect(func() { handler(p.view(value)) })
+			}
+		} else {
+			if defaultCatch != nil {
+				g.protect(func() { defaultCatch(err) })
+			}
+			if catch != nil {
+				g.protect(func() { catch(err) })
+			}
+		}
+		for _, handler := range finally {
+			g.protect(handler)
+		}
+	})
+}
+
+// view returns the settled value as handed to one consumer: a copy made by clone
+// if the promise has one, or the value itself.
+func (p *Promise[T]) view(value T) T {
+	if p.clone == nil {
+		return value
+	}
+	return p.clone(value)
+}
+
+// await blocks until the promise settles and returns its outcome.
+// It starts a lazy promise that has not started yet.
+func (p *Promise[T]) await() (T, error) {
+	p.start()
+	<-p.done
+	return p.view(p.value), p.err
+}
+
+// start runs the executor of a lazy promise the first time it is consumed.
+// It does nothing for other promises.
+func (p *Promise[T]) start() {
+	if p.lazyStart != nil {
+		p.lazyOnce.Do(p.lazyStart)
+	}
+}
+
+// onSettled runs fn through the Scheduler once the promise settles, or straight
+// away if it already has. Nothing waits on the promise in the meantime, so a
+// promise that never settles costs no goroutine. It does not start a lazy promise.
+func (p *Promise[T]) onSettled(fn func()) {
+	p.mutex.Lock()
+	if !p.settled {
+		p.subscribers = append(p.subscribers, fn)
+		p.mutex.Unlock()
+		return
+	}
+	p.mutex.Unlock()
+	goTracked(fn)
+}
+
+// whenSettled calls fn with the outcome of the promise once it settles, starting
+// a lazy promise that has not started yet.
+func (p *Promise[T]) whenSettled(fn func(T, error)) {
+	p.onSettled(func() {
+		fn(p.view(p.value), p.err)
+	})
+	p.start()
+}
+
+// subscribe calls onFulfilled or onRejected once the promise settles. Unlike Then
+// and Catch it does not replace the handlers registered on the promise, so any
+// number of combinators can safely watch the same promise.
+func (p *Promise[T]) subscribe(onFulfilled func(T), onRejected func(error)) {
+	p.whenSettled(func(value T, err error) {
+		if err != nil {
+			onRejected(err)
+			return
+		}
+		onFulfilled(value)
+	})
 }
 
 // Then sets the success handler for the promise.
 // It returns the promise itself to allow for chaining `Catch`.
+// If the promise has already fulfilled, the handler runs straight away.
 func (p *Promise[T]) Then(handler func(T)) *Promise[T] {
 	p.mutex.Lock()
-	defer p.mutex.Unlock()
 	p.then = handler
+	fulfilled := p.settled && p.err == nil
+	p.mutex.Unlock()
+
+	if fulfilled {
+		p.runSettled(func() { handler(p.view(p.value)) })
+	}
+	p.start()
 	return p
 }
 
-// Catch sets the error handler for the promise.
-// It returns the promise itself to allow for chaining `Finally`.
-func (p *Promise[T]) Catch(handler func(error)) *Promise[T] {
+// OnceThen registers a success handler under key that runs at most once, however
+// many times it is registered. Only the first handler registered under a key is
+// kept; registering again with the same key is ignored, while handlers under
+// distinct keys each run once when the promise fulfills. The key is what makes two
+// registrations the same, since function values cannot be compared. Unlike Then,
+// OnceThen does not replace the success handler, and a later Then does not remove
+// handlers registered with OnceThen.
+// It returns the promise itself to allow for chaining `Catch`.
+func (p *Promise[T]) OnceThen(key string, handler func(T)) *Promise[T] {
+	p.mutex.Lock()
+	if _, seen := p.onceKeys[key]; seen {
+		p.mutex.Unlock()
+		p.start()
+		return p
+	}
+	if p.onceKeys == nil {
+		p.onceKeys = make(map[string]struct{})
+	}
+	p.onceKeys[key] = struct{}{}
+	if !p.settled {
+		p.onceThen = append(p.onceThen, handler)
+	}
+	fulfilled := p.settled && p.err == nil
+	p.mutex.Unlock()
+
+	if fulfilled {
+		p.runSettled(func() { handler(p.view(p.value)) })
+	}
+	p.start()
+	return p
+}
+
+// runSettled runs a handler attached after the promise has settled. Promises
+// created with NewPromiseSync run it inline on the calling goroutine; all others
+// run it on a new goroutine tracked by the group.
+func (p *Promise[T]) runSettled(handler func()) {
+	if !p.syncMode {
+		p.group.spawn(handler)
+		return
+	}
+	p.group.protect(handler)
+}
+
+// ThenReturn returns a new promise that fulfills with value once p fulfills,
+// so a chain can short-circuit to a value that is already available.
+// If p rejects, the returned promise rejects with the same error.
+// Unlike Replace, ThenReturn keeps the chain's type.
+func (p *Promise[T]) ThenReturn(value T) *Promise[T] {
+	return Replace(p, value)
+}
+
+// Inspect registers fn to observe the outcome of the promise, whether it fulfills
+// or rejects, without altering or consuming the chain. fn is called exactly once
+// with the settled result, through the Scheduler. Inspect does not start a lazy
+// promise, and nothing waits on the promise in the meantime, so neither
+// WaitForPromises nor the group's Wait waits for fn.
+// It returns the promise itself to allow for chaining.
+func (p *Promise[T]) Inspect(fn func(PromiseResult[T])) *Promise[T] {
+	p.onSettled(func() {
+		p.group.protect(func() {
+			value, err := p.view(p.value), p.err
+			fn(PromiseResult[T]{Value: value, Error: err, Fulfilled: err == nil})
+		})
+	})
+	return p
+}
+
+// status reports the current state of the promise for diagnostics.
+func (p *Promise[T]) status() promiseStatus {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
-	p.catch = handler
+	switch {
+	case !p.settled:
+		return statusPending
+	case p.err != nil:
+		return statusRejected
+	default:
+		return statusFulfilled
+	}
+}
+
+// Detach removes the promise from its group's wait accounting, so WaitForPromises
+// and PromiseGroup.Wait no longer block on it. The executor keeps running and the
+// handlers of a detached promise still fire when it settles, but nothing waits for
+// them. Detach has no effect once the promise has settled.
+func (p *Promise[T]) Detach() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.settled || p.detached {
+		return
+	}
+	p.detached = true
+	p.group.wg.Done()
+}
+
+// Named gives the promise a human-readable name. The name is shown by String and
+// used to identify the promise in ExportDOT output, leak reports and the errors of
+// combinators such as AllIndexed. It returns the promise itself to allow for chaining.
+func (p *Promise[T]) Named(name string) *Promise[T] {
+	p.mutex.Lock()
+	p.name = name
+	tracked := p.tracked
+	p.mutex.Unlock()
+
+	if tracked {
+		renamePending(p.id, name)
+	}
 	return p
 }
 
-// Finally adds a handler that will be called regardless of whether the promise
-// resolves or rejects.
-func (p *Promise[T]) Finally(handler func()) {
+// Name returns the name given to the promise with Named, or "" if it has none.
+func (p *Promise[T]) Name() string {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
+	return p.name
+}
 
-	oldThen := p.then
-	oldCatch := p.catch
+// String describes the promise by its ID, name and current state.
+func (p *Promise[T]) String() string {
+	if name := p.Name(); name != "" {
+		return fmt.Sprintf("promise #%d %q (%s)", p.id, name, p.status())
+	}
+	return fmt.Sprintf("promise #%d (%s)", p.id, p.status())
+}
 
-	if oldThen != nil {
-		p.then = func(value T) {
-			oldThen(value)
-			handler()
-		}
-	} else {
-		p.then = func(_ T) {
-			handler()
+// ID returns the unique identifier assigned to the promise when it was created.
+func (p *Promise[T]) ID() uint64 {
+	return p.id
+}
+
+// Context returns the context the promise was created with.
+// Promises not created with NewPromiseWithContext return context.Background().
+// Once the promise has settled and its handlers and pending ChainCtx steps have
+// run, the context is canceled with context.Canceled to detach it from its parent,
+// so handlers attached after that see a canceled context.
+func (p *Promise[T]) Context() context.Context {
+	return p.ctx
+}
+
+// ThenWithContext sets a success handler that also receives the promise's context,
+// giving it access to request-scoped values such as trace ids.
+// It returns the promise itself to allow for chaining `Catch`.
+func (p *Promise[T]) ThenWithContext(handler func(context.Context, T)) *Promise[T] {
+	return p.Then(func(value T) {
+		handler(p.ctx, value)
+	})
+}
+
+// ThenCtx sets a success handler that is skipped if ctx is already done when the
+// promise fulfills, so no expensive work starts after the consumer has given up.
+// The handler receives ctx so it can abort cooperatively once it is running.
+// It returns the promise itself to allow for chaining `Catch`.
+func (p *Promise[T]) ThenCtx(ctx context.Context, handler func(context.Context, T)) *Promise[T] {
+	return p.Then(func(value T) {
+		if ctx.Err() != nil {
+			return
 		}
+		handler(ctx, value)
+	})
+}
+
+// Catch sets the error handler for the promise.
+// It returns the promise itself to allow for chaining `Finally`.
+// If the promise has already rejected, the handler runs straight away.
+func (p *Promise[T]) Catch(handler func(error)) *Promise[T] {
+	p.mutex.Lock()
+	p.catch = handler
+	p.caught = true
+	rejected := p.settled && p.err != nil
+	p.mutex.Unlock()
+
+	if rejected {
+		p.runSettled(func() { handler(p.err) })
 	}
+	p.start()
+	return p
+}
 
-	if oldCatch != nil {
-		p.catch = func(err error) {
-			oldCatch(err)
-			handler()
-		}
+// Finally adds a handler that will be called regardless of whether the promise
+// resolves or rejects. It runs after the Then or Catch handler, on the same goroutine.
+// Multiple Finally handlers run in the order they were registered (FIFO), each one
+// after the previous has returned. This also holds for a handler registered after
+// the promise has settled: it runs as soon as every earlier Finally handler has
+// returned, without waiting for Then or Catch handlers registered late.
+// Finally handlers are independent of the executor's own finally function.
+// It returns the promise itself to allow for further chaining.
+func (p *Promise[T]) Finally(handler func()) *Promise[T] {
+	p.mutex.Lock()
+	settled := p.settled
+	var previous, next chan struct{}
+	if settled {
+		previous, next = p.finallyTail, make(chan struct{})
+		p.finallyTail = next
 	} else {
-		p.catch = func(_ error) {
+		p.finally = append(p.finally, handler)
+	}
+	p.mutex.Unlock()
+
+	if settled {
+		p.runSettled(func() {
+			defer close(next)
+			<-previous
 			handler()
-		}
+		})
 	}
+	p.start()
+	return p
 }
@@ -0,0 +1,299 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOnceThenSameKeyRunsOnce(t *testing.T) {
+	p, resolve := delayed[int]()
+	var calls atomic.Int32
+	handler := func(int) { calls.Add(1) }
+
+	p.OnceThen("count", handler).OnceThen("count", handler)
+	resolve(1)
+	p.Await()
+	// Registering after settlement must not run it again either.
+	p.OnceThen("count", handler)
+	WaitForPromises()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestOnceThenDistinctKeysEachRun(t *testing.T) {
+	p, resolve := delayed[int]()
+	var a, b, then atomic.Int32
+
+	p.OnceThen("a", func(int) { a.Add(1) }).OnceThen("b", func(int) { b.Add(1) })
+	p.Then(func(int) { then.Add(1) })
+	resolve(1)
+	p.Await()
+	WaitForPromises()
+
+	if a.Load() != 1 || b.Load() != 1 || then.Load() != 1 {
+		t.Fatalf("a=%d b=%d then=%d, want 1 each", a.Load(), b.Load(), then.Load())
+	}
+}
+
+// onceThenCounter is package level so that the literals below capture nothing, which
+// makes the compiler share one function value between them.
+var onceThenCounter atomic.Int32
+
+func TestOnceThenNonCapturingLiterals(t *testing.T) {
+	onceThenCounter.Store(0)
+	p, resolve := delayed[int]()
+	for i := range 3 {
+		p.OnceThen(fmt.Sprint("step ", i), func(int) { onceThenCounter.Add(1) })
+	}
+	for range 3 {
+		p.OnceThen("repeated", func(int) { onceThenCounter.Add(1) })
+	}
+	resolve(1)
+	p.Await()
+	WaitForPromises()
+
+	if n := onceThenCounter.Load(); n != 4 {
+		t.Fatalf("handlers ran %d times, want 4: one per distinct key", n)
+	}
+}
+
+func TestNewPromiseSyncRunsHandlersInAttachmentOrder(t *testing.T) {
+	var order []string
+	p := NewPromiseSync[int](func(resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+	})
+	p.Then(func(int) { order = append(order, "then") }).
+		Finally(func() { order = append(order, "finally 1") }).
+		Finally(func() { order = append(order, "finally 2") })
+
+	// No synchronization: every handler must have returned before its
+	// registration call did.
+	want := []string{"then", "finally 1", "finally 2"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("handlers ran in order %v, want %v", order, want)
+	}
+}
+
+func TestNewPromiseSyncRejectedRunsCatchInline(t *testing.T) {
+	boom := errors.New("boom")
+	var order []string
+	Reject[int](boom).
+		Then(func(int) { order = append(order, "then") }).
+		Catch(func(err error) { order = append(order, "catch") }).
+		Finally(func() { order = append(order, "finally") })
+
+	want := []string{"catch", "finally"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("handlers ran in order %v, want %v", order, want)
+	}
+}
+
+func BenchmarkResolvedThen(b *testing.B) {
+	b.Run("NewPromise", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			done := make(chan struct{})
+			p := NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+				resolve(1)
+			})
+			p.Await()
+			p.Then(func(int) { close(done) })
+			<-done
+		}
+	})
+	b.Run("NewPromiseSync", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			done := make(chan struct{})
+			p := NewPromiseSync[int](func(resolve func(int), reject func(error), finally func()) {
+				resolve(1)
+			})
+			p.Then(func(int) { close(done) })
+			<-done
+		}
+	})
+}
+
+func TestExecutorFinallyAfterSettleKeepsOutcome(t *testing.T) {
+	boom := errors.New("boom")
+	fulfilled := NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+		finally()
+	})
+	rejected := NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+		reject(boom)
+		finally()
+	})
+
+	if value, err := fulfilled.Await(); err != nil || value != 1 {
+		t.Fatalf("fulfilled: Await = %d, %v; want 1, nil", value, err)
+	}
+	if _, err := rejected.Await(); !errors.Is(err, boom) {
+		t.Fatalf("rejected: Await error = %v, want boom", err)
+	}
+}
+
+func TestExecutorFinallyWithoutSettleRejects(t *testing.T) {
+	_, err := NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+		finally()
+	}).Await()
+	if !errors.Is(err, ErrExecutorFinished) {
+		t.Fatalf("Await error = %v, want ErrExecutorFinished", err)
+	}
+}
+
+func TestFinallyHandlersRunInRegistrationOrder(t *testing.T) {
+	p, resolve := delayed[int]()
+	var mutex sync.Mutex
+	var order []string
+	record := func(name string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		order = append(order, name)
+	}
+
+	release := make(chan struct{})
+	p.Finally(func() {
+		<-release
+		record("first")
+	}).Finally(func() {
+		record("second")
+	}).Finally(func() {
+		record("third")
+	})
+	resolve(1)
+	<-p.Done()
+
+	// Registered after settlement while the first handler is still blocked,
+	// the late handler must wait for all three.
+	lateDone := make(chan struct{})
+	p.Finally(func() {
+		record("late")
+		close(lateDone)
+	})
+	select {
+	case <-lateDone:
+		t.Fatal("late Finally handler ran before earlier handlers returned")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-lateDone
+	want := []string{"first", "second", "third", "late"}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !slices.Equal(order, want) {
+		t.Fatalf("Finally handlers ran in order %v, want %v", order, want)
+	}
+}
+
+// TestAwaitPanicRepanic runs itself in a subprocess, because under PanicRepanic
+// a panicking executor crashes the program. The child awaits such a promise;
+// the parent checks that it crashes with the panic rather than hanging in Await.
+func TestAwaitPanicRepanic(t *testing.T) {
+	if os.Getenv("PROMISE_TEST_REPANIC") == "1" {
+		SetPanicPolicy(PanicRepanic)
+		p := NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+			panic("repanic boom")
+		})
+		_, err := p.Await()
+		fmt.Fprintln(os.Stderr, "await returned:", err)
+		// Give the re-raised panic time to crash the process.
+		time.Sleep(10 * time.Second)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestAwaitPanicRepanic$")
+	cmd.Env = append(os.Environ(), "PROMISE_TEST_REPANIC=1")
+	out, err := cmd.CombinedOutput()
+
+	if ctx.Err() != nil {
+		t.Fatalf("child hung instead of crashing:\n%s", out)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("child exited with %v, want a crash", err)
+	}
+	if !strings.Contains(string(out), "panic: repanic boom") {
+		t.Fatalf("child output does not contain the panic:\n%s", out)
+	}
+}
+
+func TestPanickingHandlerDoesNotSkipFinally(t *testing.T) {
+	var once, finally atomic.Int32
+	p, resolve := delayed[int]()
+	p.Then(func(int) { panic("then boom") }).
+		OnceThen("once", func(int) { once.Add(1) }).
+		Finally(func() { finally.Add(1) })
+	resolve(1)
+	WaitForPromises()
+
+	if once.Load() != 1 || finally.Load() != 1 {
+		t.Fatalf("after a panicking Then: OnceThen ran %d times, Finally ran %d times; want 1 each", once.Load(), finally.Load())
+	}
+
+	// The same holds for a panicking Catch.
+	finally.Store(0)
+	Reject[int](errors.New("boom")).
+		Catch(func(error) { panic("catch boom") }).
+		Finally(func() { finally.Add(1) })
+	if n := finally.Load(); n != 1 {
+		t.Fatalf("after a panicking Catch: Finally ran %d times, want 1", n)
+	}
+}
+
+func TestInspectDoesNotHoldDetachedPromise(t *testing.T) {
+	g := NewPromiseGroup()
+	release := make(chan struct{})
+	defer close(release)
+	p := NewPromiseIn[int](g, func(resolve func(int), reject func(error), finally func()) {
+		<-release
+		resolve(1)
+	})
+	p.Detach()
+	p.Inspect(func(PromiseResult[int]) {})
+
+	waited := make(chan struct{})
+	go func() {
+		g.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked on a detached promise with an Inspect")
+	}
+}
+
+func TestInspectDoesNotStartLazy(t *testing.T) {
+	var runs atomic.Int32
+	p := Lazy(func() (int, error) {
+		runs.Add(1)
+		return 1, nil
+	})
+	inspected := make(chan PromiseResult[int], 1)
+	p.Inspect(func(r PromiseResult[int]) { inspected <- r })
+
+	time.Sleep(10 * time.Millisecond)
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("Inspect started the lazy promise: factory ran %d times", n)
+	}
+
+	p.Await()
+	if r := <-inspected; !r.Fulfilled || r.Value != 1 {
+		t.Fatalf("Inspect saw %+v, want fulfilled with 1", r)
+	}
+}
@@ -0,0 +1,54 @@
+// Package promisetest provides helpers for testing code built on promises.
+package promisetest
+
+import (
+	"testing"
+	"time"
+
+	"promise/pkg"
+)
+
+// leakGracePeriod is how long AssertNoLeaks lets goroutines finish returning
+// after the promises they served have completed.
+const leakGracePeriod = time.Second
+
+// AssertNoLeaks waits for all promises in the default group to complete and then
+// fails the test if any goroutine started by the promise package, such as an
+// executor, handler or settlement watcher, is still running, for example an
+// executor that never returns.
+func AssertNoLeaks(t testing.TB) {
+	t.Helper()
+	pkg.WaitForPromises()
+
+	// Goroutines release the wait group just before they return, so give
+	// them a moment to finish.
+	deadline := time.Now().Add(leakGracePeriod)
+	for pkg.ActiveGoroutines() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := pkg.ActiveGoroutines(); n > 0 {
+		t.Errorf("promise: %d goroutine(s) still running", n)
+	}
+}
+
+// RequireResolved awaits p and returns its value, stopping the test with t.Fatalf
+// if p rejected.
+func RequireResolved[T any](t testing.TB, p *pkg.Promise[T]) T {
+	t.Helper()
+	value, err := p.Await()
+	if err != nil {
+		t.Fatalf("promise: expected %v to resolve, but it rejected: %v", p, err)
+	}
+	return value
+}
+
+// RequireRejected awaits p and returns its rejection error, stopping the test with
+// t.Fatalf if p resolved.
+func RequireRejected[T any](t testing.TB, p *pkg.Promise[T]) error {
+	t.Helper()
+	value, err := p.Await()
+	if err == nil {
+		t.Fatalf("promise: expected %v to reject, but it resolved with %v", p, value)
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	"context"
+)
+
+// Limiter is a rate limiter that blocks until an event is allowed to happen.
+// *rate.Limiter from golang.org/x/time/rate satisfies it, so the package does not
+// need to depend on a particular implementation.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// RateLimited returns a promise that waits for a token from limiter before calling
+// factory, and then settles with the outcome of the promise factory returns.
+// If ctx ends while waiting, factory is never called and the promise rejects
+// with the limiter's error.
+func RateLimited[T any](ctx context.Context, limiter Limiter, factory func() *Promise[T]) *Promise[T] {
+	return NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
+		if err := limiter.Wait(ctx); err != nil {
+			reject(err)
+			return
+		}
+		value, err := factory().await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(value)
+	})
+}
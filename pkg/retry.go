@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetryOptions describes how a failing operation is retried.
+type RetryOptions struct {
+	// Attempts is the maximum number of times the operation runs.
+	// Values below 1 run it once.
+	Attempts int
+	// Delay is the wait between the first and second attempts.
+	Delay time.Duration
+	// Backoff multiplies the delay after every failed attempt.
+	// Values below 1 keep the delay constant.
+	Backoff float64
+	// MaxDelay caps every delay between attempts, including the first, when it
+	// is non-zero.
+	MaxDelay time.Duration
+	// ShouldRetry reports whether a failed attempt should be retried.
+	// When nil, every error is retried.
+	ShouldRetry func(error) bool
+}
+
+// NewRetryingPromise creates a promise that runs factory, rerunning it according to
+// opts while it fails. The promise settles once with the final outcome, so consumers
+// see an ordinary promise and need not know that it retries.
+// When every attempt fails, or ShouldRetry rejects an error, it rejects with an
+// error that reports the number of attempts and wraps the last error, so errors.Is
+// and errors.As still match it.
+func NewRetryingPromise[T any](opts RetryOptions, factory func() (T, error)) *Promise[T] {
+	return NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
+		attempts := max(opts.Attempts, 1)
+		delay := opts.Delay
+
+		for attempt := 1; ; attempt++ {
+			value, err := factory()
+			if err == nil {
+				resolve(value)
+				return
+			}
+			if attempt >= attempts || (opts.ShouldRetry != nil && !opts.ShouldRetry(err)) {
+				reject(fmt.Errorf("failed after %d attempt(s): %w", attempt, err))
+				return
+			}
+
+			if opts.MaxDelay > 0 && delay > opts.MaxDelay {
+				delay = opts.MaxDelay
+			}
+			time.Sleep(delay)
+			if opts.Backoff > 1 {
+				delay = time.Duration(float64(delay) * opts.Backoff)
+			}
+		}
+	})
+}
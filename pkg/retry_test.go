@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryWrapsLastError(t *testing.T) {
+	last := errors.New("last")
+	attempt := 0
+	_, err := NewRetryingPromise(RetryOptions{Attempts: 3}, func() (int, error) {
+		attempt++
+		if attempt == 3 {
+			return 0, last
+		}
+		return 0, errors.New("earlier")
+	}).Await()
+
+	if !errors.Is(err, last) {
+		t.Fatalf("Await error = %v, want it to wrap the last error", err)
+	}
+	if !strings.Contains(err.Error(), "3 attempt(s)") {
+		t.Fatalf("Await error = %v, want it to report 3 attempts", err)
+	}
+}
+
+func TestRetryMaxDelayCapsFirstDelay(t *testing.T) {
+	started := time.Now()
+	_, err := NewRetryingPromise(RetryOptions{Attempts: 2, Delay: time.Hour, MaxDelay: time.Millisecond}, func() (int, error) {
+		return 0, errors.New("fail")
+	}).Await()
+
+	if err == nil {
+		t.Fatal("Await succeeded, want the failure")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("retry took %v, want MaxDelay to cap the first delay", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Scheduler runs the executors and handlers of promises.
+type Scheduler interface {
+	// Go runs task asynchronously.
+	Go(task func())
+}
+
+// goroutineScheduler is the default Scheduler, which runs every task on a new goroutine.
+type goroutineScheduler struct{}
+
+// Go runs task on a new goroutine.
+func (goroutineScheduler) Go(task func()) {
+	go task()
+}
+
+// schedulerHolder wraps a Scheduler so it can be stored in an atomic.Pointer.
+type schedulerHolder struct {
+	scheduler Scheduler
+}
+
+var activeScheduler atomic.Pointer[schedulerHolder]
+
+// SetScheduler replaces the Scheduler used to run executors and handlers.
+// Passing nil restores the default, which starts a goroutine per task.
+func SetScheduler(s Scheduler) {
+	if s == nil {
+		activeScheduler.Store(nil)
+		return
+	}
+	activeScheduler.Store(&schedulerHolder{scheduler: s})
+}
+
+// currentScheduler returns the Scheduler in use.
+func currentScheduler() Scheduler {
+	if holder := activeScheduler.Load(); holder != nil {
+		return holder.scheduler
+	}
+	return goroutineScheduler{}
+}
+
+// ManualScheduler is a Scheduler for tests that queues executors and handlers
+// instead of running them, and only runs them when the test calls Step or RunAll.
+// This gives a test full control over the interleaving of promise work, so races
+// such as a promise settling before its handler is attached can be reproduced
+// deterministically. Tasks run on the goroutine that calls Step or RunAll, so a
+// task that blocks waiting on another queued task will never return. Settlement
+// callbacks are queued as tasks too, and combinators such as All, Race, Any,
+// AllSettled, AllIndexed and RaceIndex never block, so they can be stepped through;
+// executors that call Await on a pending promise cannot.
+type ManualScheduler struct {
+	mutex sync.Mutex
+	queue []func()
+}
+
+// NewManualScheduler creates and returns an empty ManualScheduler.
+func NewManualScheduler() *ManualScheduler {
+	return &ManualScheduler{}
+}
+
+// Go queues task until it is run by Step or RunAll.
+func (s *ManualScheduler) Go(task func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queue = append(s.queue, task)
+}
+
+// Step runs the oldest queued task. It reports false if there was nothing to run.
+func (s *ManualScheduler) Step() bool {
+	s.mutex.Lock()
+	if len(s.queue) == 0 {
+		s.mutex.Unlock()
+		return false
+	}
+	task := s.queue[0]
+	s.queue = s.queue[1:]
+	s.mutex.Unlock()
+
+	task()
+	return true
+}
+
+// RunAll runs queued tasks, including those queued by the tasks it runs, until
+// the queue is empty. It returns the number of tasks run.
+func (s *ManualScheduler) RunAll() int {
+	n := 0
+	for s.Step() {
+		n++
+	}
+	return n
+}
+
+// Pending returns the number of queued tasks.
+func (s *ManualScheduler) Pending() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.queue)
+}
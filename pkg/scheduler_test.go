@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// useManualScheduler installs a ManualScheduler for the duration of the test.
+func useManualScheduler(t *testing.T) *ManualScheduler {
+	t.Helper()
+	WaitForPromises()
+	s := NewManualScheduler()
+	SetScheduler(s)
+	t.Cleanup(func() {
+		s.RunAll()
+		SetScheduler(nil)
+	})
+	return s
+}
+
+func resolveWith[T any](value T) func(resolve func(T), reject func(error), finally func()) {
+	return func(resolve func(T), reject func(error), finally func()) {
+		resolve(value)
+	}
+}
+
+func TestManualSchedulerRunsNothingUntilStepped(t *testing.T) {
+	s := useManualScheduler(t)
+
+	p := NewPromise(resolveWith(1))
+	if _, ok := p.Peek(); ok {
+		t.Fatal("executor ran before Step")
+	}
+	if !s.Step() {
+		t.Fatal("Step found no queued executor")
+	}
+	if r, ok := p.Peek(); !ok || r.Value != 1 {
+		t.Fatalf("Peek after Step = %+v, %v", r, ok)
+	}
+}
+
+func TestManualSchedulerCombinatorsSettleAfterRunAll(t *testing.T) {
+	s := useManualScheduler(t)
+
+	a := NewPromise(resolveWith(1))
+	b := NewPromise(resolveWith(2))
+	all := All(a, b)
+	race := Race(a, b)
+	indexed := AllIndexed(a, b)
+	raceIndex := RaceIndex(a, b)
+	settled := AllSettled(a, b)
+	anyOf := Any(a, b)
+	values, _ := a.ToChannel()
+
+	s.RunAll()
+	if n := s.Pending(); n != 0 {
+		t.Fatalf("%d tasks pending after RunAll", n)
+	}
+
+	if r, ok := all.Peek(); !ok || len(r.Value) != 2 || r.Value[1] != 2 {
+		t.Errorf("All = %+v, %v", r, ok)
+	}
+	if _, ok := race.Peek(); !ok {
+		t.Error("Race not settled")
+	}
+	if r, ok := indexed.Peek(); !ok || r.Value[1].Value != 2 {
+		t.Errorf("AllIndexed = %+v, %v", r, ok)
+	}
+	if _, ok := raceIndex.Peek(); !ok {
+		t.Error("RaceIndex not settled")
+	}
+	if _, ok := settled.Peek(); !ok {
+		t.Error("AllSettled not settled")
+	}
+	if _, ok := anyOf.Peek(); !ok {
+		t.Error("Any not settled")
+	}
+	select {
+	case v := <-values:
+		if v != 1 {
+			t.Errorf("ToChannel value = %d", v)
+		}
+	default:
+		t.Error("ToChannel delivered nothing")
+	}
+}
+
+func TestManualSchedulerContextCancellation(t *testing.T) {
+	s := useManualScheduler(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPromiseWithContext[int](ctx, func(ctx context.Context, resolve func(int), reject func(error), finally func()) {})
+	cancel()
+	<-p.Done()
+	if _, err := p.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Await = %v, want context.Canceled", err)
+	}
+	s.RunAll()
+}
+
+func TestManualSchedulerInspect(t *testing.T) {
+	s := useManualScheduler(t)
+	p := NewPromise[int](resolveWith(3))
+	var seen PromiseResult[int]
+	p.Inspect(func(r PromiseResult[int]) { seen = r })
+
+	// Inspect parks nothing, so stepping the executor and then the watcher
+	// completes without blocking.
+	s.RunAll()
+	if !seen.Fulfilled || seen.Value != 3 {
+		t.Fatalf("Inspect saw %+v, want fulfilled with 3", seen)
+	}
+}
@@ -0,0 +1,39 @@
+package pkg
+
+import (
+	"context"
+)
+
+// Semaphore limits how many holders can use a resource at once, with acquisition
+// expressed as a promise so it composes with Chain and the combinators.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore creates a semaphore with n slots. An n below one is treated as one.
+func NewSemaphore(n int) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, max(n, 1))}
+}
+
+// Acquire returns a promise that fulfills once a slot has been taken, or rejects
+// with context.Cause(ctx) if ctx ends first, in which case no slot is held.
+// Every fulfilled Acquire must be paired with a call to Release.
+func (s *Semaphore) Acquire(ctx context.Context) *Promise[struct{}] {
+	return NewPromise[struct{}](func(resolve func(struct{}), reject func(error), finally func()) {
+		select {
+		case s.slots <- struct{}{}:
+			resolve(struct{}{})
+		case <-ctx.Done():
+			reject(context.Cause(ctx))
+		}
+	})
+}
+
+// Release gives back a slot taken by Acquire. It panics if no slot is held.
+func (s *Semaphore) Release() {
+	select {
+	case <-s.slots:
+	default:
+		panic("promise: semaphore released without being acquired")
+	}
+}
@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"sync"
+)
+
+// Flight deduplicates concurrent requests that share a key, so that identical
+// in-flight requests are served by a single underlying promise.
+type Flight[K comparable, T any] struct {
+	mutex    sync.Mutex
+	inFlight map[K]*Promise[T]
+}
+
+// SingleFlight creates and returns an empty Flight.
+func SingleFlight[K comparable, T any]() *Flight[K, T] {
+	return &Flight[K, T]{inFlight: make(map[K]*Promise[T])}
+}
+
+// Do returns the promise currently in flight for key, or calls factory to start one.
+// While that promise is pending every caller with the same key receives it;
+// once it settles the key is freed and the next call runs factory again.
+// factory is called while the flight is locked, so it should only start the
+// work and return its promise.
+func (f *Flight[K, T]) Do(key K, factory func() *Promise[T]) *Promise[T] {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if p, ok := f.inFlight[key]; ok {
+		return p
+	}
+
+	p := factory()
+	f.inFlight[key] = p
+
+	p.onSettled(func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		// Only forget the promise if it has not been replaced in the meantime.
+		if f.inFlight[key] == p {
+			delete(f.inFlight, key)
+		}
+	})
+
+	return p
+}
@@ -1,44 +1,127 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // All waits for all promises to be resolved, or for any to be rejected.
 // Returns a new Promise that resolves with an array of all results or rejects with the first error.
+// The handlers registered on the input promises are left untouched, so the same promise can be
+// passed to several combinators, including nested ones.
+// Every handler and every Await of the returned promise receives its own copy of the
+// resolved slice, so a consumer that modifies it affects no other consumer.
 func All[T any](promises ...*Promise[T]) *Promise[[]T] {
-	return NewPromise[[]T](func(resolve func([]T), reject func(error), finally func()) {
+	all := newPromise[[]T](defaultGroup, context.Background())
+	all.clone = slices.Clone[[]T]
+	all.run(func(resolve func([]T), reject func(error), finally func()) {
 		if len(promises) == 0 {
 			resolve([]T{})
 			return
 		}
 
+		// Each input writes only its own slot, and the one that brings remaining to
+		// zero observes every write, so no lock is needed. A rejection never
+		// decrements remaining, so All cannot resolve after it has rejected, and
+		// once rejected is set the remaining inputs leave results alone.
 		results := make([]T, len(promises))
-		var mu sync.Mutex
-		remaining := len(promises)
+		var remaining atomic.Int64
+		remaining.Store(int64(len(promises)))
+		var rejected atomic.Bool
 
 		for i, p := range promises {
-			idx := i // Capture loop variable
-			p.Then(func(val T) {
-				mu.Lock()
-				results[idx] = val
-				remaining--
-				if remaining == 0 {
-					mu.Unlock()
+			p.subscribe(func(val T) {
+				if rejected.Load() {
+					return
+				}
+				results[i] = val
+				if remaining.Add(-1) == 0 {
 					resolve(results)
-				} else {
-					mu.Unlock()
 				}
-			}).Catch(func(err error) {
-				mu.Lock()
-				// Only reject once
-				if remaining > 0 {
-					remaining = 0
-					mu.Unlock()
-					reject(err)
-				} else {
-					mu.Unlock()
+			}, func(err error) {
+				rejected.Store(true)
+				reject(err)
+			})
+		}
+	})
+	return all
+}
+
+// AllCancel waits for all promises to be resolved, like All, but as soon as one
+// rejects it cancels the contexts of the remaining promises so they can stop work.
+// The cancellation cause is the first rejection error. Only promises created with
+// NewPromiseWithContext can be canceled; for other inputs AllCancel behaves like All.
+func AllCancel[T any](promises ...*Promise[T]) *Promise[[]T] {
+	return NewPromise[[]T](func(resolve func([]T), reject func(error), finally func()) {
+		results := make([]T, len(promises))
+		errs := make(chan error, len(promises))
+
+		for i, p := range promises {
+			p.whenSettled(func(val T, err error) {
+				results[i] = val
+				errs <- err
+			})
+		}
+
+		for range promises {
+			if err := <-errs; err != nil {
+				for _, p := range promises {
+					p.cancelWith(err)
+				}
+				reject(err)
+				return
+			}
+		}
+		// On success every input has already settled, so there is nothing left to cancel.
+		resolve(results)
+	})
+}
+
+// AllFailFast returns a promise that behaves like All, rejecting as soon as any promise
+// rejects, together with a function for reconciling the work still in flight.
+// The function blocks until every input promise has settled and returns their results
+// in input order, so the caller can decide what to do with promises that were still
+// running when the aggregate failed.
+func AllFailFast[T any](promises ...*Promise[T]) (*Promise[[]T], func() []PromiseResult[T]) {
+	settlements := func() []PromiseResult[T] {
+		results := make([]PromiseResult[T], len(promises))
+		for i, p := range promises {
+			val, err := p.await()
+			results[i] = PromiseResult[T]{Value: val, Error: err, Fulfilled: err == nil}
+		}
+		return results
+	}
+	return All(promises...), settlements
+}
+
+// AllIndexed waits for all promises to be resolved, like All, but pairs every value
+// with the index of the promise that produced it so callers can filter or reorder
+// the results without losing that association.
+// If any promise rejects, it rejects with an *IndexedError naming the failing index.
+func AllIndexed[T any](promises ...*Promise[T]) *Promise[[]Indexed[T]] {
+	return NewPromise[[]Indexed[T]](func(resolve func([]Indexed[T]), reject func(error), finally func()) {
+		results := make([]Indexed[T], len(promises))
+		if len(promises) == 0 {
+			resolve(results)
+			return
+		}
+
+		var remaining atomic.Int64
+		remaining.Store(int64(len(promises)))
+		for i, p := range promises {
+			p.whenSettled(func(val T, err error) {
+				if err != nil {
+					reject(&IndexedError{Index: i, Name: p.Name(), Err: err})
+					return
+				}
+				results[i] = Indexed[T]{Index: i, Value: val}
+				if remaining.Add(-1) == 0 {
+					resolve(results)
 				}
 			})
 		}
@@ -57,7 +140,7 @@ func Race[T any](promises ...*Promise[T]) *Promise[T] {
 		var mu sync.Mutex
 
 		for _, p := range promises {
-			p.Then(func(val T) {
+			p.subscribe(func(val T) {
 				mu.Lock()
 				if !settled {
 					settled = true
@@ -66,7 +149,7 @@ func Race[T any](promises ...*Promise[T]) *Promise[T] {
 				} else {
 					mu.Unlock()
 				}
-			}).Catch(func(err error) {
+			}, func(err error) {
 				mu.Lock()
 				if !settled {
 					settled = true
@@ -80,6 +163,29 @@ func Race[T any](promises ...*Promise[T]) *Promise[T] {
 	})
 }
 
+// RaceIndex is like Race, but resolves with the index of the winning promise along
+// with its value. If the first promise to settle rejects, it rejects with an
+// *IndexedError carrying that promise's index.
+func RaceIndex[T any](promises ...*Promise[T]) *Promise[Indexed[T]] {
+	return NewPromise[Indexed[T]](func(resolve func(Indexed[T]), reject func(error), finally func()) {
+		if len(promises) == 0 {
+			reject(fmt.Errorf("no promises to race"))
+			return
+		}
+
+		// Settling is first-call-wins, so the first promise to report decides.
+		for i, p := range promises {
+			p.whenSettled(func(val T, err error) {
+				if err != nil {
+					reject(&IndexedError{Index: i, Name: p.Name(), Err: err})
+					return
+				}
+				resolve(Indexed[T]{Index: i, Value: val})
+			})
+		}
+	})
+}
+
 // PromiseResult represents the result of a promise that may be fulfilled or rejected
 type PromiseResult[T any] struct {
 	Value     T
@@ -87,6 +193,33 @@ type PromiseResult[T any] struct {
 	Fulfilled bool
 }
 
+// Indexed pairs a value with the index of the input promise that produced it.
+type Indexed[T any] struct {
+	Index int
+	Value T
+}
+
+// CountRejections returns the number of results that represent a rejection.
+func CountRejections[T any](results []PromiseResult[T]) int {
+	count := 0
+	for _, r := range results {
+		if !r.Fulfilled {
+			count++
+		}
+	}
+	return count
+}
+
+// AnyRejected reports whether at least one of the results represents a rejection.
+func AnyRejected[T any](results []PromiseResult[T]) bool {
+	for _, r := range results {
+		if !r.Fulfilled {
+			return true
+		}
+	}
+	return false
+}
+
 // AllSettled waits until all promises have settled (either resolved or rejected).
 // Returns a promise that resolves with an array of objects representing the settlement status of each promise.
 func AllSettled[T any](promises ...*Promise[T]) *Promise[[]PromiseResult[T]] {
@@ -97,71 +230,309 @@ func AllSettled[T any](promises ...*Promise[T]) *Promise[[]PromiseResult[T]] {
 		}
 
 		results := make([]PromiseResult[T], len(promises))
-		var mu sync.Mutex
-		remaining := len(promises)
+		var remaining atomic.Int64
+		remaining.Store(int64(len(promises)))
 
 		for i, p := range promises {
-			idx := i // Capture loop variable
-			p.Then(func(val T) {
-				mu.Lock()
-				results[idx] = PromiseResult[T]{Value: val, Fulfilled: true}
-				remaining--
-				if remaining == 0 {
-					mu.Unlock()
+			p.subscribe(func(val T) {
+				results[i] = PromiseResult[T]{Value: val, Fulfilled: true}
+				if remaining.Add(-1) == 0 {
 					resolve(results)
-				} else {
-					mu.Unlock()
 				}
-			}).Catch(func(err error) {
-				mu.Lock()
-				results[idx] = PromiseResult[T]{Error: err, Fulfilled: false}
-				remaining--
-				if remaining == 0 {
-					mu.Unlock()
+			}, func(err error) {
+				results[i] = PromiseResult[T]{Error: err, Fulfilled: false}
+				if remaining.Add(-1) == 0 {
 					resolve(results)
-				} else {
-					mu.Unlock()
 				}
 			})
 		}
 	})
 }
 
+// AllSettledMap waits until every promise in the map has settled and resolves with
+// their results keyed by the original keys. It never rejects.
+func AllSettledMap[K comparable, T any](promises map[K]*Promise[T]) *Promise[map[K]PromiseResult[T]] {
+	return NewPromise[map[K]PromiseResult[T]](func(resolve func(map[K]PromiseResult[T]), reject func(error), finally func()) {
+		results := make(map[K]PromiseResult[T], len(promises))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+
+		for key, p := range promises {
+			wg.Add(1)
+			p.whenSettled(func(val T, err error) {
+				defer wg.Done()
+				mu.Lock()
+				results[key] = PromiseResult[T]{Value: val, Error: err, Fulfilled: err == nil}
+				mu.Unlock()
+			})
+		}
+
+		wg.Wait()
+		resolve(results)
+	})
+}
+
+// SettledSeq returns an iterator over the settlement of each promise in completion order.
+// Each step yields the promise's original index together with its result, and the
+// iteration ends once every promise has settled or the consumer stops ranging.
+// Only results that have settled but not been yielded yet are held, so memory for
+// results grows with how far the consumer falls behind, not with the number of promises.
+func SettledSeq[T any](promises ...*Promise[T]) iter.Seq2[int, PromiseResult[T]] {
+	return func(yield func(int, PromiseResult[T]) bool) {
+		type settlement struct {
+			index  int
+			result PromiseResult[T]
+		}
+
+		// Watchers queue their settlement and signal ready without ever blocking;
+		// once the consumer has stopped they drop it instead. The state sits
+		// behind one pointer to keep each watcher's closure small.
+		q := &struct {
+			mutex   sync.Mutex
+			queued  []settlement
+			stopped bool
+			ready   chan struct{}
+		}{ready: make(chan struct{}, 1)}
+		for i, p := range promises {
+			p.whenSettled(func(val T, err error) {
+				q.mutex.Lock()
+				if q.stopped {
+					q.mutex.Unlock()
+					return
+				}
+				q.queued = append(q.queued, settlement{index: i, result: PromiseResult[T]{Value: val, Error: err, Fulfilled: err == nil}})
+				q.mutex.Unlock()
+				select {
+				case q.ready <- struct{}{}:
+				default:
+				}
+			})
+		}
+		defer func() {
+			q.mutex.Lock()
+			q.stopped = true
+			q.queued = nil
+			q.mutex.Unlock()
+		}()
+
+		// The consumed batch is cleared and reused as the next queue, so the
+		// memory held tracks the largest backlog rather than every settlement.
+		var batch []settlement
+		for remaining := len(promises); remaining > 0; {
+			<-q.ready
+			q.mutex.Lock()
+			batch, q.queued = q.queued, batch[:0]
+			q.mutex.Unlock()
+
+			for _, s := range batch {
+				remaining--
+				if !yield(s.index, s.result) {
+					return
+				}
+			}
+			clear(batch)
+		}
+	}
+}
+
+// AllSettledStream is like AllSettled, but also calls onSettled with the index and result
+// of each promise as soon as it settles, in completion order. onSettled is never called
+// concurrently. The returned promise resolves with all results in input order.
+func AllSettledStream[T any](onSettled func(int, PromiseResult[T]), promises ...*Promise[T]) *Promise[[]PromiseResult[T]] {
+	return NewPromise[[]PromiseResult[T]](func(resolve func([]PromiseResult[T]), reject func(error), finally func()) {
+		results := make([]PromiseResult[T], len(promises))
+		for i, result := range SettledSeq(promises...) {
+			onSettled(i, result)
+			results[i] = result
+		}
+		resolve(results)
+	})
+}
+
+// ReduceAsSettled folds the results of the promises into an accumulator in completion
+// order, calling reducer with each result, fulfilled or rejected, as soon as it settles.
+// reducer is never called concurrently. The returned promise resolves with the final
+// accumulator once every promise has settled, and never rejects.
+func ReduceAsSettled[T, A any](init A, reducer func(acc A, r PromiseResult[T]) A, promises ...*Promise[T]) *Promise[A] {
+	return NewPromise[A](func(resolve func(A), reject func(error), finally func()) {
+		acc := init
+		for _, result := range SettledSeq(promises...) {
+			acc = reducer(acc, result)
+		}
+		resolve(acc)
+	})
+}
+
+// SettledSummary counts the outcomes of a batch of promises.
+type SettledSummary struct {
+	Total     int
+	Fulfilled int
+	Rejected  int
+}
+
+// AllSettledStreamSummary is like AllSettledStream, but does not keep the results:
+// it only calls onSettled for each one and resolves with a summary of the counts.
+// A result is held only until onSettled has been called with it, so as long as
+// onSettled keeps up, memory for results stays small however large the batch is.
+func AllSettledStreamSummary[T any](onSettled func(int, PromiseResult[T]), promises ...*Promise[T]) *Promise[SettledSummary] {
+	return NewPromise[SettledSummary](func(resolve func(SettledSummary), reject func(error), finally func()) {
+		var summary SettledSummary
+		for i, result := range SettledSeq(promises...) {
+			onSettled(i, result)
+			summary.Total++
+			if result.Fulfilled {
+				summary.Fulfilled++
+			} else {
+				summary.Rejected++
+			}
+		}
+		resolve(summary)
+	})
+}
+
 // Any returns a promise that fulfills when any of the input promises fulfills, with this first fulfillment value.
 // Rejects only if all promises reject, with an AggregateError containing all rejection reasons.
 func Any[T any](promises ...*Promise[T]) *Promise[T] {
 	return NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
 		if len(promises) == 0 {
-			reject(fmt.Errorf("all promises rejected"))
+			reject(&AggregateError{})
 			return
 		}
 
-		var mu sync.Mutex
-		remaining := len(promises)
+		// Settling is first-call-wins, so the first fulfillment resolves and later
+		// ones are ignored. Rejections only count down, so remaining reaches zero
+		// only if every promise rejected.
+		var remaining atomic.Int64
+		remaining.Store(int64(len(promises)))
 		errors := make([]error, len(promises))
 
 		for i, p := range promises {
-			idx := i // Capture loop variable
-			p.Then(func(val T) {
-				mu.Lock()
-				if remaining > 0 {
-					remaining = 0
-					mu.Unlock()
-					resolve(val)
-				} else {
-					mu.Unlock()
+			p.subscribe(resolve, func(err error) {
+				errors[i] = err
+				if remaining.Add(-1) == 0 {
+					reject(&AggregateError{Errors: errors})
 				}
-			}).Catch(func(err error) {
-				mu.Lock()
-				errors[idx] = err
+			})
+		}
+	})
+}
+
+// FirstSuccess returns a promise that fulfills with the first fulfillment among the promises,
+// ignoring rejections. It rejects with an *AggregateError if every promise rejects, or with
+// ErrTimeout if no promise has fulfilled within d.
+func FirstSuccess[T any](d time.Duration, promises ...*Promise[T]) *Promise[T] {
+	return NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
+		if len(promises) == 0 {
+			reject(&AggregateError{})
+			return
+		}
+
+		type outcome struct {
+			index int
+			value T
+			err   error
+		}
+
+		outcomes := make(chan outcome, len(promises))
+		for i, p := range promises {
+			p.whenSettled(func(val T, err error) {
+				outcomes <- outcome{index: i, value: val, err: err}
+			})
+		}
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		errors := make([]error, len(promises))
+		for range promises {
+			select {
+			case o := <-outcomes:
+				if o.err == nil {
+					resolve(o.value)
+					return
+				}
+				errors[o.index] = o.err
+			case <-timer.C:
+				reject(fmt.Errorf("%w: no promise fulfilled within %v", ErrTimeout, d))
+				return
+			}
+		}
+		reject(&AggregateError{Errors: errors})
+	})
+}
+
+// AllFromChannel collects promises from ch until it is closed and waits for all of them,
+// like All. It resolves with the values in the order the promises were received, or
+// rejects as soon as any received promise rejects, without waiting for ch to close.
+func AllFromChannel[T any](ch <-chan *Promise[T]) *Promise[[]T] {
+	return AllFromChannelCap(ch, 0)
+}
+
+// AllFromChannelCap is AllFromChannel with a hint of how many promises ch will deliver.
+// The result slice is preallocated to that capacity, which avoids repeated growth
+// for large fan-ins. The hint does not limit how many promises are accepted.
+func AllFromChannelCap[T any](ch <-chan *Promise[T], expected int) *Promise[[]T] {
+	return NewPromise[[]T](func(resolve func([]T), reject func(error), finally func()) {
+		// Every promise is watched as soon as it is received. The mutex guards
+		// results, which grows while earlier promises are already settling, and
+		// whichever of the last fulfillment or the close of ch comes second
+		// resolves.
+		var (
+			mutex     sync.Mutex
+			results   = make([]T, 0, expected)
+			remaining int
+			closed    bool
+		)
+		for p := range ch {
+			var zero T
+			mutex.Lock()
+			i := len(results)
+			results = append(results, zero)
+			remaining++
+			mutex.Unlock()
+
+			p.subscribe(func(val T) {
+				mutex.Lock()
+				results[i] = val
 				remaining--
-				if remaining == 0 {
-					mu.Unlock()
-					reject(fmt.Errorf("all promises rejected: %v", errors))
-				} else {
-					mu.Unlock()
+				done := closed && remaining == 0
+				mutex.Unlock()
+				if done {
+					resolve(results)
 				}
+			}, reject)
+		}
+
+		mutex.Lock()
+		closed = true
+		done := remaining == 0
+		mutex.Unlock()
+		if done {
+			resolve(results)
+		}
+	})
+}
+
+// FanOut applies every processor to value concurrently and resolves with their results
+// in processor order. It rejects with the first error if any processor's promise rejects.
+func FanOut[T, R any](value T, processors ...func(T) *Promise[R]) *Promise[[]R] {
+	return NewPromise[[]R](func(resolve func([]R), reject func(error), finally func()) {
+		results := make([]R, len(processors))
+		errs := make(chan error, len(processors))
+
+		for i, process := range processors {
+			process(value).whenSettled(func(val R, err error) {
+				results[i] = val
+				errs <- err
 			})
 		}
+
+		for range processors {
+			if err := <-errs; err != nil {
+				reject(err)
+				return
+			}
+		}
+		resolve(results)
 	})
 }
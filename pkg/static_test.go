@@ -0,0 +1,292 @@
+package pkg
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// delayed returns a promise and a function that resolves it, so tests control
+// when inputs settle relative to the combinators watching them.
+func delayed[T any]() (*Promise[T], func(T)) {
+	ready := make(chan func(T))
+	p := NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
+		ready <- resolve
+	})
+	return p, <-ready
+}
+
+func TestAllNestedStress(t *testing.T) {
+	const rounds = 200
+	for range rounds {
+		a, resolveA := delayed[int]()
+		b, resolveB := delayed[int]()
+		c, resolveC := delayed[int]()
+
+		// The same inputs are watched by nested and sibling combinators, and
+		// also carry a user handler that must not be replaced.
+		var userCalls atomic.Int32
+		b.Then(func(int) { userCalls.Add(1) })
+
+		nested := All(All(a, b), All(b, c), All(a, b, c))
+		race := Race(a, b, c)
+		settled := AllSettled(a, b, c)
+
+		var wg sync.WaitGroup
+		for i, resolve := range []func(int){resolveA, resolveB, resolveC} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resolve(i + 1)
+			}()
+		}
+		wg.Wait()
+
+		got, err := nested.Await()
+		if err != nil {
+			t.Fatalf("nested All rejected: %v", err)
+		}
+		want := [][]int{{1, 2}, {2, 3}, {1, 2, 3}}
+		for i := range want {
+			for j := range want[i] {
+				if got[i][j] != want[i][j] {
+					t.Fatalf("nested All = %v, want %v", got, want)
+				}
+			}
+		}
+		if _, err := race.Await(); err != nil {
+			t.Fatalf("Race rejected: %v", err)
+		}
+		if results, _ := settled.Await(); CountRejections(results) != 0 {
+			t.Fatalf("AllSettled = %+v", results)
+		}
+		WaitForPromises()
+		if n := userCalls.Load(); n != 1 {
+			t.Fatalf("user handler on shared input called %d times, want 1", n)
+		}
+	}
+}
+
+func TestAllNestedRejection(t *testing.T) {
+	boom := errors.New("boom")
+	for range 100 {
+		a, resolveA := delayed[int]()
+		b := NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+			reject(boom)
+		})
+		outer := All(All(a, b), All(a))
+		go resolveA(1)
+		if _, err := outer.Await(); !errors.Is(err, boom) {
+			t.Fatalf("nested All = %v, want %v", err, boom)
+		}
+	}
+	WaitForPromises()
+}
+
+func TestAllResultIsolation(t *testing.T) {
+	a := Resolve(1)
+	b := Resolve(2)
+	all := All(a, b)
+
+	mutated := make(chan struct{})
+	all.Then(func(values []int) {
+		values[0] = 99
+		close(mutated)
+	})
+	<-mutated
+
+	first, _ := all.Await()
+	if first[0] != 1 {
+		t.Fatalf("Await after a handler mutated its slice = %v, want [1 2]", first)
+	}
+	first[1] = 42
+	second, _ := all.Await()
+	if second[0] != 1 || second[1] != 2 {
+		t.Fatalf("second Await = %v, want [1 2]", second)
+	}
+	var subscribed []int
+	done := make(chan struct{})
+	all.subscribe(func(values []int) {
+		subscribed = values
+		close(done)
+	}, nil)
+	<-done
+	if subscribed[0] != 1 || subscribed[1] != 2 {
+		t.Fatalf("subscriber saw %v, want [1 2]", subscribed)
+	}
+	if other, _ := All(a, b).Await(); other[0] != 1 {
+		t.Fatalf("All over the same inputs = %v, want [1 2]", other)
+	}
+}
+
+// benchmarkInputs creates n pending promises and the functions that resolve them.
+func benchmarkInputs(n int) ([]*Promise[int], []func(int)) {
+	promises := make([]*Promise[int], n)
+	resolvers := make([]func(int), n)
+	for i := range n {
+		promises[i], resolvers[i] = delayed[int]()
+	}
+	return promises, resolvers
+}
+
+func benchmarkCombinator[R any](b *testing.B, combine func(...*Promise[int]) *Promise[R]) {
+	const n = 10000
+	for range b.N {
+		b.StopTimer()
+		promises, resolvers := benchmarkInputs(n)
+		b.StartTimer()
+
+		combined := combine(promises...)
+		// Settle the inputs concurrently so completions contend with each other.
+		for i, resolve := range resolvers {
+			go resolve(i)
+		}
+		combined.Await()
+	}
+}
+
+func BenchmarkAll10000(b *testing.B) {
+	benchmarkCombinator(b, All[int])
+}
+
+func BenchmarkAllSettled10000(b *testing.B) {
+	benchmarkCombinator(b, AllSettled[int])
+}
+
+func BenchmarkAny10000(b *testing.B) {
+	benchmarkCombinator(b, Any[int])
+}
+
+func TestAllFromChannelFailsFast(t *testing.T) {
+	boom := errors.New("boom")
+	pending, resolve := delayed[int]()
+	ch := make(chan *Promise[int], 2)
+	ch <- pending
+	ch <- Reject[int](boom)
+	// ch stays open and pending is unsettled until the test ends, so only failing
+	// fast can settle. Both are released afterwards so WaitForPromises can return.
+	all := AllFromChannel(ch)
+	defer resolve(0)
+	defer close(ch)
+
+	select {
+	case <-all.Done():
+	case <-time.After(time.Second):
+		t.Fatal("AllFromChannel did not reject while an earlier promise was pending")
+	}
+	if _, err := all.Await(); !errors.Is(err, boom) {
+		t.Fatalf("Await error = %v, want boom", err)
+	}
+}
+
+func TestAllFromChannelOrder(t *testing.T) {
+	inputs := make([]*Promise[int], 3)
+	resolvers := make([]func(int), 3)
+	ch := make(chan *Promise[int], len(inputs))
+	for i := range inputs {
+		inputs[i], resolvers[i] = delayed[int]()
+		ch <- inputs[i]
+	}
+	close(ch)
+	all := AllFromChannelCap(ch, len(inputs))
+
+	// Settle in reverse so that completion order differs from receive order.
+	for i := len(resolvers) - 1; i >= 0; i-- {
+		resolvers[i](i)
+	}
+	values, err := all.Await()
+	if err != nil || !slices.Equal(values, []int{0, 1, 2}) {
+		t.Fatalf("Await = %v, %v; want [0 1 2], nil", values, err)
+	}
+}
+
+func BenchmarkAllFromChannel(b *testing.B) {
+	const n = 1000
+	for _, bench := range []struct {
+		name     string
+		expected int
+	}{{"NoHint", 0}, {"Hint", n}} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				ch := make(chan *Promise[int], n)
+				for i := range n {
+					ch <- Resolve(i)
+				}
+				close(ch)
+				AllFromChannelCap(ch, bench.expected).Await()
+			}
+		})
+	}
+}
+
+func TestSettledSeqCompletionOrder(t *testing.T) {
+	promises, resolvers := benchmarkInputs(3)
+	go func() {
+		for _, i := range []int{2, 0, 1} {
+			resolvers[i](i * 10)
+			// Let the consumer see each settlement before the next one.
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	var order []int
+	for i, result := range SettledSeq(promises...) {
+		if !result.Fulfilled || result.Value != i*10 {
+			t.Fatalf("result for %d = %+v, want fulfilled with %d", i, result, i*10)
+		}
+		order = append(order, i)
+	}
+	if !slices.Equal(order, []int{2, 0, 1}) {
+		t.Fatalf("yielded indexes %v, want [2 0 1]", order)
+	}
+}
+
+func TestSettledSeqEarlyBreak(t *testing.T) {
+	promises, resolvers := benchmarkInputs(3)
+	resolvers[1](1)
+	for i := range SettledSeq(promises...) {
+		if i != 1 {
+			t.Fatalf("first yielded index = %d, want 1", i)
+		}
+		break
+	}
+
+	// Settlements after the consumer stopped are dropped without blocking.
+	resolvers[0](0)
+	resolvers[2](2)
+	promises[0].Await()
+	promises[2].Await()
+}
+
+// BenchmarkAllSettledStreamSummary reports the memory a large summarized batch
+// allocates when onSettled keeps up, which should not include a buffered result
+// for every input.
+func BenchmarkAllSettledStreamSummary(b *testing.B) {
+	const n, chunk = 100000, 1000
+	b.ReportAllocs()
+	for range b.N {
+		b.StopTimer()
+		promises, resolvers := benchmarkInputs(n)
+		b.StartTimer()
+
+		var seen atomic.Int64
+		summary := AllSettledStreamSummary(func(int, PromiseResult[int]) {
+			seen.Add(1)
+		}, promises...)
+		// Settle the inputs a chunk at a time, letting onSettled catch up in between.
+		for start := 0; start < n; start += chunk {
+			for i := start; i < start+chunk; i++ {
+				resolvers[i](i)
+			}
+			for seen.Load() < int64(start+chunk) {
+				runtime.Gosched()
+			}
+		}
+		summary.Await()
+	}
+}
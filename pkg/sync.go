@@ -1,12 +1,9 @@
 package pkg
 
-import (
-	"sync"
-)
-
-// Wait for all promise to complete
-var wg sync.WaitGroup
+// defaultGroup holds every promise created with NewPromise.
+var defaultGroup = NewPromiseGroup()
 
+// WaitForPromises blocks until all promises in the default group have completed.
 func WaitForPromises() {
-	wg.Wait()
+	defaultGroup.Wait()
 }
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Poll returns a promise that calls check immediately and then every interval until
+// it reports done, resolving with the value check returned. The promise rejects if
+// check returns an error, or with context.Cause(ctx) if ctx ends first. An interval
+// that is not positive rejects the promise without calling check.
+func Poll[T any](ctx context.Context, interval time.Duration, check func() (T, bool, error)) *Promise[T] {
+	return NewPromiseWithContext[T](ctx, func(ctx context.Context, resolve func(T), reject func(error), finally func()) {
+		if interval <= 0 {
+			reject(fmt.Errorf("promise: poll interval must be positive, got %v", interval))
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			value, done, err := check()
+			if err != nil {
+				reject(err)
+				return
+			}
+			if done {
+				resolve(value)
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				reject(context.Cause(ctx))
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Hedge reduces tail latency by staggering redundant attempts. It calls factories[0]
+// immediately and, each time delay passes without a fulfillment, starts the next
+// factory; an attempt that rejects starts the next one straight away. The returned
+// promise fulfills with the first value produced by any attempt, or rejects with an
+// *AggregateError once every factory has been tried and failed. Attempts still
+// running when one fulfills are left to settle on their own.
+func Hedge[T any](delay time.Duration, factories ...func() *Promise[T]) *Promise[T] {
+	return NewPromise[T](func(resolve func(T), reject func(error), finally func()) {
+		if len(factories) == 0 {
+			reject(&AggregateError{})
+			return
+		}
+
+		type outcome struct {
+			index int
+			value T
+			err   error
+		}
+
+		outcomes := make(chan outcome, len(factories))
+		started := 0
+		startNext := func() {
+			i := started
+			started++
+			p := factories[i]()
+			p.whenSettled(func(val T, err error) {
+				outcomes <- outcome{index: i, value: val, err: err}
+			})
+		}
+		startNext()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		errors := make([]error, len(factories))
+		for failed := 0; failed < len(factories); {
+			select {
+			case o := <-outcomes:
+				if o.err == nil {
+					resolve(o.value)
+					return
+				}
+				errors[o.index] = o.err
+				failed++
+				if started < len(factories) {
+					startNext()
+					timer.Reset(delay)
+				}
+			case <-timer.C:
+				if started < len(factories) {
+					startNext()
+					timer.Reset(delay)
+				}
+			}
+		}
+		reject(&AggregateError{Errors: errors})
+	})
+}
+
+// Throttle returns a function that runs factory at most once per interval. The
+// first call runs factory; calls within interval of that run return the same
+// promise, whether it is still pending or already settled, and the first call
+// after interval has passed runs factory again. The returned function is safe
+// for concurrent use.
+func Throttle[T any](interval time.Duration, factory func() *Promise[T]) func() *Promise[T] {
+	var (
+		mutex   sync.Mutex
+		last    *Promise[T]
+		lastRun time.Time
+	)
+	return func() *Promise[T] {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if last == nil || time.Since(lastRun) >= interval {
+			last = factory()
+			lastRun = time.Now()
+		}
+		return last
+	}
+}
+
+// MinDuration returns a new promise that settles with p's outcome, but never
+// earlier than minimum after MinDuration was called; a faster outcome is held back
+// until then. This masks how long the work took, for example to avoid timing side
+// channels on login endpoints. If p takes longer than minimum, the returned promise
+// settles as soon as p does.
+func MinDuration[T any](p *Promise[T], minimum time.Duration) *Promise[T] {
+	timer := time.NewTimer(minimum)
+	return derive[T, T](p, func(resolve func(T), reject func(error), finally func()) {
+		defer timer.Stop()
+		value, err := p.await()
+		<-timer.C
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(value)
+	})
+}
@@ -0,0 +1,23 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPollRejectsNonPositiveInterval(t *testing.T) {
+	var calls atomic.Int32
+	_, err := Poll(context.Background(), 0, func() (int, bool, error) {
+		calls.Add(1)
+		return 0, true, nil
+	}).Await()
+
+	if err == nil || !strings.Contains(err.Error(), "poll interval must be positive") {
+		t.Fatalf("Await error = %v, want a poll interval error", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("check called %d times, want 0", n)
+	}
+}
@@ -0,0 +1,219 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+)
+
+// Replace returns a new promise that fulfills with value once p fulfills,
+// discarding p's own result. The value may be of a different type than p's,
+// which makes Replace useful for switching the type of a chain.
+// If p rejects, the returned promise rejects with the same error.
+// The returned promise belongs to the same group as p.
+func Replace[T, R any](p *Promise[T], value R) *Promise[R] {
+	return derive[T, R](p, func(resolve func(R), reject func(error), finally func()) {
+		if _, err := p.await(); err != nil {
+			reject(err)
+			return
+		}
+		resolve(value)
+	})
+}
+
+// ThenMap returns a new promise that fulfills with fn applied to p's value.
+// If p rejects, or fn returns an error, the returned promise rejects with that error.
+func ThenMap[T, R any](p *Promise[T], fn func(T) (R, error)) *Promise[R] {
+	return derive[T, R](p, func(resolve func(R), reject func(error), finally func()) {
+		value, err := p.await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		mapped, err := fn(value)
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(mapped)
+	})
+}
+
+// MapOrOriginal is a best-effort ThenMap: it fulfills with fn applied to p's value,
+// but if fn fails it fulfills with the original value instead and reports the error
+// to onError, which may be nil. Because the fallback is the original value, fn must
+// map T to T. If p rejects, the returned promise rejects with the same error.
+func MapOrOriginal[T any](p *Promise[T], fn func(T) (T, error), onError func(error)) *Promise[T] {
+	return derive[T, T](p, func(resolve func(T), reject func(error), finally func()) {
+		value, err := p.await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		mapped, err := fn(value)
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			resolve(value)
+			return
+		}
+		resolve(mapped)
+	})
+}
+
+// Chain returns a new promise that settles like the promise fn returns for p's value,
+// so async steps can be sequenced. If p rejects, fn is not called and the returned
+// promise rejects with the same error.
+func Chain[T, R any](p *Promise[T], fn func(T) *Promise[R]) *Promise[R] {
+	return derive[T, R](p, func(resolve func(R), reject func(error), finally func()) {
+		value, err := p.await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		next, err := fn(value).await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(next)
+	})
+}
+
+// ChainCtx is Chain for context-aware steps. The returned promise shares p's
+// context, which is also passed to fn, so cancelling the root of a chain cancels
+// every step built on it. A step added after p's context has been released, once
+// p settled and its handlers ran, gets a new context derived from the same parent. If the context is done before fn runs, fn is not called
+// and the returned promise rejects with the context's cause; if it ends while the
+// step is running, the returned promise rejects with the cause straight away
+// rather than waiting for the promise fn returned.
+func ChainCtx[T, R any](p *Promise[T], fn func(context.Context, T) *Promise[R]) *Promise[R] {
+	ctx := p.ctx
+	var release func()
+	if p.link != nil {
+		ctx, release = p.link.share()
+	}
+	child := newPromise[R](p.group, ctx)
+	p.group.addEdge(p.id, child.id)
+	// Keep the shared context alive until this step settles.
+	if release != nil {
+		child.onSettled(release)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		child.reject(context.Cause(ctx))
+	})
+	child.onSettled(func() { stop() })
+	child.run(func(resolve func(R), reject func(error), finally func()) {
+		value, err := p.await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		if ctx.Err() != nil {
+			reject(context.Cause(ctx))
+			return
+		}
+		next, err := fn(ctx, value).await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(next)
+	})
+	return child
+}
+
+// Compose returns a reusable transformation that applies f and then g to the value
+// of a promise through ThenMap, short-circuiting on the first error.
+func Compose[T, U, R any](f func(T) (U, error), g func(U) (R, error)) func(*Promise[T]) *Promise[R] {
+	return func(p *Promise[T]) *Promise[R] {
+		return ThenMap(ThenMap(p, f), g)
+	}
+}
+
+// MapError returns a new promise that rejects with fn applied to p's rejection reason,
+// for example to classify or wrap it. Fulfillment passes through unchanged.
+// Unlike a recovery, the returned promise always stays rejected when p rejects;
+// if fn returns nil, the original error is kept.
+func MapError[T any](p *Promise[T], fn func(error) error) *Promise[T] {
+	return derive[T, T](p, func(resolve func(T), reject func(error), finally func()) {
+		value, err := p.await()
+		if err == nil {
+			resolve(value)
+			return
+		}
+		if mapped := fn(err); mapped != nil {
+			err = mapped
+		}
+		reject(err)
+	})
+}
+
+// CatchType returns a new promise that calls handler when p rejects with an error
+// that matches type E according to errors.As. The handler can recover with a value
+// or reject with another error. Fulfillment and errors of other types pass through.
+func CatchType[T any, E error](p *Promise[T], handler func(E) (T, error)) *Promise[T] {
+	return derive[T, T](p, func(resolve func(T), reject func(error), finally func()) {
+		value, err := p.await()
+		if err == nil {
+			resolve(value)
+			return
+		}
+		var target E
+		if !errors.As(err, &target) {
+			reject(err)
+			return
+		}
+		value, err = handler(target)
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(value)
+	})
+}
+
+// NonNil returns a new promise that rejects with onNil if p fulfills with a nil pointer,
+// and otherwise passes p's outcome through unchanged. It guards chains that would
+// otherwise dereference an accidental nil result.
+func NonNil[T any](p *Promise[*T], onNil error) *Promise[*T] {
+	return derive[*T, *T](p, func(resolve func(*T), reject func(error), finally func()) {
+		value, err := p.await()
+		switch {
+		case err != nil:
+			reject(err)
+		case value == nil:
+			reject(onNil)
+		default:
+			resolve(value)
+		}
+	})
+}
+
+// Materialize returns a new promise that always fulfills, with p's outcome as a
+// PromiseResult. It moves a rejection into the value, so for example a promise can
+// pass through All without short-circuiting it. Dematerialize reverses it.
+func Materialize[T any](p *Promise[T]) *Promise[PromiseResult[T]] {
+	return derive[T, PromiseResult[T]](p, func(resolve func(PromiseResult[T]), reject func(error), finally func()) {
+		value, err := p.await()
+		resolve(PromiseResult[T]{Value: value, Error: err, Fulfilled: err == nil})
+	})
+}
+
+// Dematerialize returns a new promise that settles with the outcome held in the
+// PromiseResult p fulfills with: it fulfills with the value of a fulfilled result
+// and rejects with the error of a rejected one. If p itself rejects, the returned
+// promise rejects with the same error.
+func Dematerialize[T any](p *Promise[PromiseResult[T]]) *Promise[T] {
+	return derive[PromiseResult[T], T](p, func(resolve func(T), reject func(error), finally func()) {
+		result, err := p.await()
+		switch {
+		case err != nil:
+			reject(err)
+		case !result.Fulfilled:
+			reject(result.Error)
+		default:
+			resolve(result.Value)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestChainCtxCanceledBeforeStep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	gate := make(chan struct{})
+	root := NewPromiseWithContext[int](ctx, func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+		<-gate
+		resolve(1)
+	})
+
+	var calls atomic.Int32
+	step := ChainCtx(root, func(ctx context.Context, value int) *Promise[int] {
+		calls.Add(1)
+		return Resolve(value + 1)
+	})
+
+	cancel()
+	close(gate)
+
+	if _, err := step.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("step error = %v, want context.Canceled", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("fn called %d times after the root was cancelled, want 0", n)
+	}
+}
+
+func TestChainCtxCanceledDuringStep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	root := NewPromiseWithContext[int](ctx, func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+	})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	step := ChainCtx(root, func(ctx context.Context, value int) *Promise[int] {
+		close(started)
+		// This step ignores ctx, so only ChainCtx can end it early.
+		return NewPromise[int](func(resolve func(int), reject func(error), finally func()) {
+			<-release
+			resolve(value + 1)
+		})
+	})
+
+	<-started
+	cancel()
+
+	if _, err := step.Await(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("step error = %v, want context.Canceled", err)
+	}
+}
+
+func TestChainCtxFulfills(t *testing.T) {
+	root := NewPromiseWithContext[int](context.Background(), func(ctx context.Context, resolve func(int), reject func(error), finally func()) {
+		resolve(1)
+	})
+	step := ChainCtx(root, func(ctx context.Context, value int) *Promise[int] {
+		return Resolve(value + 1)
+	})
+
+	if value, err := step.Await(); err != nil || value != 2 {
+		t.Fatalf("Await = %d, %v; want 2, nil", value, err)
+	}
+}
+
+func TestReplaceThenMap(t *testing.T) {
+	p := Replace(Resolve(1), "two")
+	value, err := ThenMap(p, func(s string) (int, error) {
+		return len(s), nil
+	}).Await()
+	if err != nil || value != 3 {
+		t.Fatalf("Await = %d, %v; want 3, nil", value, err)
+	}
+}
+
+func TestReplaceThenMapPropagatesRejection(t *testing.T) {
+	boom := errors.New("boom")
+	var calls atomic.Int32
+	p := Replace(Reject[int](boom), "two")
+	_, err := ThenMap(p, func(s string) (int, error) {
+		calls.Add(1)
+		return len(s), nil
+	}).Await()
+	if !errors.Is(err, boom) {
+		t.Fatalf("Await error = %v, want boom", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("fn called %d times after a rejection, want 0", n)
+	}
+}
+
+func TestMapOrOriginalMapped(t *testing.T) {
+	var reported atomic.Int32
+	value, err := MapOrOriginal(Resolve(2), func(v int) (int, error) {
+		return v * 10, nil
+	}, func(error) { reported.Add(1) }).Await()
+
+	if err != nil || value != 20 {
+		t.Fatalf("Await = %d, %v; want 20, nil", value, err)
+	}
+	if n := reported.Load(); n != 0 {
+		t.Fatalf("onError called %d times, want 0", n)
+	}
+}
+
+func TestMapOrOriginalFallsBack(t *testing.T) {
+	boom := errors.New("boom")
+	var reported error
+	value, err := MapOrOriginal(Resolve(2), func(v int) (int, error) {
+		return 0, boom
+	}, func(err error) { reported = err }).Await()
+
+	if err != nil || value != 2 {
+		t.Fatalf("Await = %d, %v; want the original 2, nil", value, err)
+	}
+	if !errors.Is(reported, boom) {
+		t.Fatalf("onError received %v, want boom", reported)
+	}
+
+	// A nil onError is allowed.
+	if value, err := MapOrOriginal(Resolve(3), func(int) (int, error) {
+		return 0, boom
+	}, nil).Await(); err != nil || value != 3 {
+		t.Fatalf("Await with nil onError = %d, %v; want 3, nil", value, err)
+	}
+}
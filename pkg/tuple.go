@@ -0,0 +1,101 @@
+package pkg
+
+// Pair holds two values produced together by a single async operation.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Values returns both elements of the pair.
+func (p Pair[A, B]) Values() (A, B) {
+	return p.First, p.Second
+}
+
+// Triple holds three values produced together by a single async operation.
+type Triple[A, B, C any] struct {
+	First  A
+	Second B
+	Third  C
+}
+
+// Values returns all three elements of the triple.
+func (t Triple[A, B, C]) Values() (A, B, C) {
+	return t.First, t.Second, t.Third
+}
+
+// NewPromise2 creates a promise for an async operation that resolves with two values.
+// The values are delivered to handlers as a Pair.
+func NewPromise2[A, B any](executor func(resolve func(A, B), reject func(error))) *Promise[Pair[A, B]] {
+	return NewPromise[Pair[A, B]](func(resolve func(Pair[A, B]), reject func(error), finally func()) {
+		executor(func(a A, b B) {
+			resolve(Pair[A, B]{First: a, Second: b})
+		}, reject)
+	})
+}
+
+// NewPromise3 creates a promise for an async operation that resolves with three values.
+// The values are delivered to handlers as a Triple.
+func NewPromise3[A, B, C any](executor func(resolve func(A, B, C), reject func(error))) *Promise[Triple[A, B, C]] {
+	return NewPromise[Triple[A, B, C]](func(resolve func(Triple[A, B, C]), reject func(error), finally func()) {
+		executor(func(a A, b B, c C) {
+			resolve(Triple[A, B, C]{First: a, Second: b, Third: c})
+		}, reject)
+	})
+}
+
+// Zip waits for two promises of different types and resolves with both results as a Pair.
+// It rejects with the first error if either promise rejects.
+func Zip[A, B any](pa *Promise[A], pb *Promise[B]) *Promise[Pair[A, B]] {
+	zipped := derive[A, Pair[A, B]](pa, func(resolve func(Pair[A, B]), reject func(error), finally func()) {
+		errs := make(chan error, 2)
+		var pair Pair[A, B]
+		pa.whenSettled(func(value A, err error) {
+			pair.First = value
+			errs <- err
+		})
+		pb.whenSettled(func(value B, err error) {
+			pair.Second = value
+			errs <- err
+		})
+		for range 2 {
+			if err := <-errs; err != nil {
+				reject(err)
+				return
+			}
+		}
+		resolve(pair)
+	})
+	pa.group.addEdge(pb.id, zipped.id)
+	return zipped
+}
+
+// Zip3 waits for three promises of different types and resolves with all results as a Triple.
+// It rejects with the first error if any promise rejects.
+func Zip3[A, B, C any](pa *Promise[A], pb *Promise[B], pc *Promise[C]) *Promise[Triple[A, B, C]] {
+	zipped := derive[A, Triple[A, B, C]](pa, func(resolve func(Triple[A, B, C]), reject func(error), finally func()) {
+		errs := make(chan error, 3)
+		var triple Triple[A, B, C]
+		pa.whenSettled(func(value A, err error) {
+			triple.First = value
+			errs <- err
+		})
+		pb.whenSettled(func(value B, err error) {
+			triple.Second = value
+			errs <- err
+		})
+		pc.whenSettled(func(value C, err error) {
+			triple.Third = value
+			errs <- err
+		})
+		for range 3 {
+			if err := <-errs; err != nil {
+				reject(err)
+				return
+			}
+		}
+		resolve(triple)
+	})
+	pa.group.addEdge(pb.id, zipped.id)
+	pa.group.addEdge(pc.id, zipped.id)
+	return zipped
+}
@@ -0,0 +1,23 @@
+package pkg
+
+// Node is a node of a dependency tree processed by Walk.
+type Node[T any] struct {
+	Value    T
+	Children []*Node[T]
+}
+
+// Walk processes a dependency tree bottom-up with promises. The children of every
+// node are walked concurrently, and once all of them have fulfilled, resolve is
+// called with the node's value and the children's results in order. The returned
+// promise fulfills with the result for root, or rejects with the first error from
+// resolve in any node, in which case the ancestors of that node are not resolved.
+// A node reachable along several paths is walked once per path.
+func Walk[T, R any](root *Node[T], resolve func(self T, children []R) (R, error)) *Promise[R] {
+	children := make([]*Promise[R], len(root.Children))
+	for i, child := range root.Children {
+		children[i] = Walk(child, resolve)
+	}
+	return ThenMap(All(children...), func(results []R) (R, error) {
+		return resolve(root.Value, results)
+	})
+}